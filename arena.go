@@ -0,0 +1,255 @@
+package binarytree
+
+// ArenaTree is an ordered map with the same behaviour as Tree, whose nodes are allocated in
+// fixed size chunks and linked by int32 indexes instead of pointers. Cleared nodes are kept on
+// a free list and reused by later Sets.
+//
+// A Tree allocates every node separately, so a large tree is many small objects for the garbage
+// collector to track. An ArenaTree allocates one object per chunk of nodes and its links are not
+// pointers, which cuts allocations and GC work. Keys and values are still interfaces, and are
+// scanned by the GC as usual. An ArenaTree never returns memory to the GC, but cleared
+// nodes are reused. It holds at most 2^31-1 nodes.
+type ArenaTree struct {
+  chunks [][]arenaNode
+  root int32
+  free int32
+  used int32
+  count int
+  compare Comparator
+}
+
+// arenaNode is a node in an ArenaTree. Nodes on the free list are linked through left.
+type arenaNode struct {
+  key Comparable
+  value interface{}
+  left int32
+  right int32
+}
+
+const (
+  arenaChunkBits = 12
+  arenaChunkSize = 1 << arenaChunkBits
+  arenaChunkMask = arenaChunkSize - 1
+  // The index of a missing node
+  arenaNil int32 = -1
+  arenaMaxNodes = 1<<31 - 1
+)
+
+// Return a new empty arena tree
+func NewArenaTree() *ArenaTree {
+  return NewArenaTreeWithComparator(DefaultComparator)
+}
+
+// Return a new empty arena tree that orders its keys with the supplied Comparator
+func NewArenaTreeWithComparator(cmp Comparator) *ArenaTree {
+  if cmp == nil { cmp = DefaultComparator }
+  return &ArenaTree{ root: arenaNil, free: arenaNil, compare: cmp }
+}
+
+// Return the node at the supplied index.
+func (me *ArenaTree) node(index int32) *arenaNode {
+  return &me.chunks[index >> arenaChunkBits][index & arenaChunkMask]
+}
+
+// Return the index of a new node with the supplied key and value, from the free list if possible.
+func (me *ArenaTree) alloc(key Comparable, value interface{}) int32 {
+  index := me.free
+  if index != arenaNil {
+    me.free = me.node(index).left
+  } else {
+    if me.used == arenaMaxNodes { panic("binarytree: ArenaTree is full") }
+    if int(me.used) == len(me.chunks) * arenaChunkSize { me.chunks = append(me.chunks, make([]arenaNode, arenaChunkSize)) }
+    index = me.used
+    me.used++
+  }
+  *me.node(index) = arenaNode{ key: key, value: value, left: arenaNil, right: arenaNil }
+  return index
+}
+
+// Put the node at the supplied index on the free list, dropping its key and value.
+func (me *ArenaTree) release(index int32) {
+  *me.node(index) = arenaNode{ left: me.free, right: arenaNil }
+  me.free = index
+}
+
+// Return the index of the node with the supplied key and the link that points to it.
+// If the key is not found, return arenaNil and the link where it would be added.
+func (me *ArenaTree) find(key Comparable) (int32, *int32) {
+  link := &me.root
+  for *link != arenaNil {
+    node := me.node(*link)
+    c := me.compare(key, node.key)
+    if c == 0 { return *link, link }
+    if c < 0 {
+      link = &node.left
+    } else {
+      link = &node.right
+    }
+  }
+  return arenaNil, link
+}
+
+// Add the supplied key and value to the tree. If the key already exists, the value will be overwritten.
+func (me *ArenaTree) Set(key Comparable, value interface{}) {
+  index, link := me.find(key)
+  if index != arenaNil {
+    me.node(index).value = value
+    return
+  }
+  // Adding a chunk does not move existing nodes, so link is still valid after alloc
+  *link = me.alloc(key, value)
+  me.count++
+}
+
+// Get the value associated with the supplied key. Return (true, value) if found,
+// (false, nil) if not.
+func (me *ArenaTree) Get(key Comparable) (bool, interface{}) {
+  index, _ := me.find(key)
+  if index == arenaNil { return false, nil }
+  return true, me.node(index).value
+}
+
+// Clear (Delete) the supplied key
+func (me *ArenaTree) Clear(key Comparable) {
+  index, link := me.find(key)
+  if index == arenaNil { return }
+  node := me.node(index)
+  switch {
+    case node.left == arenaNil:
+      *link = node.right
+    case node.right == arenaNil:
+      *link = node.left
+    default:
+      // Move the successor's key and value here and unlink the successor instead
+      successorLink := &node.right
+      for me.node(*successorLink).left != arenaNil { successorLink = &me.node(*successorLink).left }
+      successor := *successorLink
+      node.key, node.value = me.node(successor).key, me.node(successor).value
+      *successorLink = me.node(successor).right
+      index = successor
+  }
+  me.release(index)
+  me.count--
+}
+
+// Return the number of keys in the tree.
+func (me *ArenaTree) Len() int {
+  return me.count
+}
+
+// Return the key and value with the next smallest key than the supplied key.
+// If a smaller key exists, return (true, key, value), otherwise return (false, nil, nil).
+func (me *ArenaTree) Previous(key Comparable) (bool, Comparable, interface{}) {
+  found := arenaNil
+  for index := me.root; index != arenaNil; {
+    node := me.node(index)
+    if me.compare(node.key, key) < 0 {
+      found, index = index, node.right
+    } else {
+      index = node.left
+    }
+  }
+  if found == arenaNil { return false, nil, nil }
+  return true, me.node(found).key, me.node(found).value
+}
+
+// Return the key and value with the next largest key than the supplied key.
+// If a larger key exists, return (true, key, value), otherwise return (false, nil, nil).
+func (me *ArenaTree) Next(key Comparable) (bool, Comparable, interface{}) {
+  found := arenaNil
+  for index := me.root; index != arenaNil; {
+    node := me.node(index)
+    if me.compare(node.key, key) > 0 {
+      found, index = index, node.left
+    } else {
+      index = node.right
+    }
+  }
+  if found == arenaNil { return false, nil, nil }
+  return true, me.node(found).key, me.node(found).value
+}
+
+// Return the first (lowest) key and value in the tree, or nil, nil if the tree is empty.
+func (me *ArenaTree) First() (Comparable, interface{}) {
+  if me.root == arenaNil { return nil, nil }
+  node := me.node(me.root)
+  for node.left != arenaNil { node = me.node(node.left) }
+  return node.key, node.value
+}
+
+// Return the last (highest) key and value in the tree, or nil, nil if the tree is empty.
+func (me *ArenaTree) Last() (Comparable, interface{}) {
+  if me.root == arenaNil { return nil, nil }
+  node := me.node(me.root)
+  for node.right != arenaNil { node = me.node(node.right) }
+  return node.key, node.value
+}
+
+// Iterate the tree with the function in the supplied direction
+func (me *ArenaTree) Walk(iterator Iterator, forward bool) {
+  me.walk(func(node *arenaNode) { iterator(node.key, node.value) }, nil, nil, forward)
+}
+
+// Iterate the tree for all keys between the two keys, inclusive
+func (me *ArenaTree) WalkRange(iterator Iterator, from Comparable, to Comparable, forward bool) {
+  me.walk(func(node *arenaNode) { iterator(node.key, node.value) }, from, to, forward)
+}
+
+// Call iterator for each node with a key in the range from, to in the supplied direction. A nil bound is open.
+// The walk uses an explicit stack, so degenerate trees do not recurse deeply.
+func (me *ArenaTree) walk(iterator func(node *arenaNode), from Comparable, to Comparable, forward bool) {
+  // Walking backward swaps the bounds and the children
+  low, high := from, to
+  sign := 1
+  if !forward { low, high, sign = to, from, -1 }
+  near := func(node *arenaNode) int32 { if forward { return node.left }; return node.right }
+  far := func(node *arenaNode) int32 { if forward { return node.right }; return node.left }
+
+  stack := []int32{}
+  index := me.root
+  for {
+    for index != arenaNil {
+      node := me.node(index)
+      if low != nil && sign * me.compare(node.key, low) < 0 {
+        index = far(node)
+        continue
+      }
+      stack = append(stack, index)
+      index = near(node)
+    }
+    if len(stack) == 0 { return }
+    index = stack[len(stack)-1]
+    stack = stack[:len(stack)-1]
+    node := me.node(index)
+    if high != nil && sign * me.compare(node.key, high) > 0 { return }
+    iterator(node)
+    index = far(node)
+  }
+}
+
+// Balance the tree.
+func (me *ArenaTree) Balance() {
+  indexes := make([]int32, 0, me.count)
+  stack := []int32{}
+  for index := me.root; index != arenaNil || len(stack) > 0; {
+    for index != arenaNil {
+      stack = append(stack, index)
+      index = me.node(index).left
+    }
+    index = stack[len(stack)-1]
+    stack = stack[:len(stack)-1]
+    indexes = append(indexes, index)
+    index = me.node(index).right
+  }
+  me.root = me.link(indexes)
+}
+
+// Link the supplied nodes, which must be in key order, into a balanced tree and return its root.
+func (me *ArenaTree) link(indexes []int32) int32 {
+  if len(indexes) == 0 { return arenaNil }
+  mid := len(indexes) / 2
+  node := me.node(indexes[mid])
+  node.left = me.link(indexes[:mid])
+  node.right = me.link(indexes[mid+1:])
+  return indexes[mid]
+}
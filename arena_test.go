@@ -0,0 +1,230 @@
+package binarytree
+
+import (
+  "fmt"
+  "math/rand"
+  "runtime"
+  "testing"
+  "time"
+  "github.com/stretchr/testify/assert"
+)
+
+func arenaKeys(tree *ArenaTree, forward bool) []int {
+  keys := []int{}
+  tree.Walk(func(key Comparable, value interface{}) { keys = append(keys, int(key.(IntKey))) }, forward)
+  return keys
+}
+
+func TestArenaTreeSetGetClear(t *testing.T) {
+  tree := NewArenaTree()
+  found, _ := tree.Get(IntKey(1))
+  assert.False(t, found)
+  tree.Clear(IntKey(1))
+
+  for _, key := range []int{ 4, 2, 6, 1, 3, 5, 7 } { tree.Set(IntKey(key), key*10) }
+  tree.Set(IntKey(4), "four")
+  assert.Equal(t, 7, tree.Len())
+  found, value := tree.Get(IntKey(4))
+  assert.True(t, found)
+  assert.Equal(t, "four", value)
+
+  // Leaf, one child and two children
+  tree.Clear(IntKey(1))
+  tree.Clear(IntKey(2))
+  tree.Clear(IntKey(4))
+  assert.Equal(t, []int{ 3, 5, 6, 7 }, arenaKeys(tree, true))
+  assert.Equal(t, []int{ 7, 6, 5, 3 }, arenaKeys(tree, false))
+  assert.Equal(t, 4, tree.Len())
+  _, value = tree.Get(IntKey(5))
+  assert.Equal(t, 50, value)
+}
+
+func TestArenaTreeFreeList(t *testing.T) {
+  tree := NewArenaTree()
+  for i:=0; i<arenaChunkSize+10; i++ { tree.Set(IntKey(i), i) }
+  assert.Equal(t, 2, len(tree.chunks))
+  for i:=0; i<100; i++ { tree.Clear(IntKey(i)) }
+  used := tree.used
+  for i:=0; i<100; i++ { tree.Set(IntKey(-i-1), i) }
+
+  // Cleared nodes are reused before new ones
+  assert.Equal(t, used, tree.used)
+  assert.Equal(t, arenaChunkSize+10, tree.Len())
+  _, value := tree.Get(IntKey(-100))
+  assert.Equal(t, 99, value)
+
+  tree.Set(IntKey(-101), "new")
+  assert.Equal(t, used+1, tree.used)
+}
+
+func TestArenaTreeNextPrevious(t *testing.T) {
+  tree := NewArenaTree()
+  found, _, _ := tree.Next(IntKey(0))
+  assert.False(t, found)
+  first, _ := tree.First()
+  assert.Nil(t, first)
+  last, _ := tree.Last()
+  assert.Nil(t, last)
+
+  for _, key := range []int{ 40, 20, 60, 10, 30, 50, 70 } { tree.Set(IntKey(key), key) }
+  found, key, value := tree.Next(IntKey(30))
+  assert.True(t, found)
+  assert.Equal(t, IntKey(40), key)
+  assert.Equal(t, 40, value)
+  _, key, _ = tree.Next(IntKey(35))
+  assert.Equal(t, IntKey(40), key)
+  found, _, _ = tree.Next(IntKey(70))
+  assert.False(t, found)
+
+  _, key, _ = tree.Previous(IntKey(30))
+  assert.Equal(t, IntKey(20), key)
+  _, key, _ = tree.Previous(IntKey(100))
+  assert.Equal(t, IntKey(70), key)
+  found, _, _ = tree.Previous(IntKey(10))
+  assert.False(t, found)
+
+  first, _ = tree.First()
+  assert.Equal(t, IntKey(10), first)
+  last, _ = tree.Last()
+  assert.Equal(t, IntKey(70), last)
+}
+
+func TestArenaTreeWalkRange(t *testing.T) {
+  tree := NewArenaTree()
+  for _, key := range []int{ 4, 2, 6, 1, 3, 5, 7 } { tree.Set(IntKey(key), key) }
+  walk := func(from int, to int, forward bool) []int {
+    keys := []int{}
+    tree.WalkRange(func(key Comparable, value interface{}) { keys = append(keys, int(key.(IntKey))) }, IntKey(from), IntKey(to), forward)
+    return keys
+  }
+  assert.Equal(t, []int{ 1, 2, 3 }, walk(-5, 3, true))
+  assert.Equal(t, []int{ 5, 6, 7 }, walk(5, 10, true))
+  assert.Equal(t, []int{ 2, 3, 4, 5, 6 }, walk(2, 6, true))
+  assert.Equal(t, []int{ 3, 2, 1 }, walk(-5, 3, false))
+  assert.Equal(t, []int{ 7, 6, 5 }, walk(5, 10, false))
+  assert.Equal(t, []int{ 6, 5, 4, 3, 2 }, walk(2, 6, false))
+  assert.Equal(t, []int{}, walk(8, 10, true))
+}
+
+func TestArenaTreeBalance(t *testing.T) {
+  tree := NewArenaTree()
+  for i:=1; i<=7; i++ { tree.Set(IntKey(i), i) }
+  tree.Balance()
+  assert.Equal(t, IntKey(4), tree.node(tree.root).key)
+  assert.Equal(t, []int{ 1, 2, 3, 4, 5, 6, 7 }, arenaKeys(tree, true))
+  NewArenaTree().Balance()
+}
+
+func TestArenaTreeComparator(t *testing.T) {
+  tree := NewArenaTreeWithComparator(ReverseComparator(DefaultComparator))
+  for i:=1; i<=3; i++ { tree.Set(IntKey(i), i) }
+  assert.Equal(t, []int{ 3, 2, 1 }, arenaKeys(tree, true))
+}
+
+func TestArenaTreeMatchesTree(t *testing.T) {
+  r := rand.New(rand.NewSource(1))
+  arena, tree := NewArenaTree(), NewTree()
+  for i:=0; i<20000; i++ {
+    key := IntKey(r.Intn(500))
+    switch r.Intn(3) {
+      case 0, 1:
+        arena.Set(key, i)
+        tree.Set(key, i)
+      case 2:
+        arena.Clear(key)
+        tree.Clear(key)
+    }
+    if i % 1000 == 0 {
+      arena.Balance()
+      expected, actual := []interface{}{}, []interface{}{}
+      tree.Walk(func(key Comparable, value interface{}) { expected = append(expected, key, value) }, true)
+      arena.Walk(func(key Comparable, value interface{}) { actual = append(actual, key, value) }, true)
+      assert.Equal(t, expected, actual)
+      assert.Equal(t, len(expected)/2, arena.Len())
+    }
+    probe := IntKey(r.Intn(520) - 10)
+    _, treeNext, _ := tree.Next(probe)
+    _, arenaNext, _ := arena.Next(probe)
+    assert.Equal(t, treeNext, arenaNext)
+  }
+}
+
+// benchmarkArena adapts ArenaTree to benchmarkMap
+type benchmarkArena struct {
+  tree *ArenaTree
+}
+
+func (me *benchmarkArena) Set(key int, value interface{}) { me.tree.Set(IntKey(key), value) }
+func (me *benchmarkArena) Get(key int) (bool, interface{}) { return me.tree.Get(IntKey(key)) }
+func (me *benchmarkArena) Clear(key int) { me.tree.Clear(IntKey(key)) }
+
+func (me *benchmarkArena) Next(key int) (bool, int) {
+  found, next, _ := me.tree.Next(IntKey(key))
+  if !found { return false, 0 }
+  return true, int(next.(IntKey))
+}
+
+func (me *benchmarkArena) Walk(iterator func(key int, value interface{})) {
+  me.tree.Walk(func(key Comparable, value interface{}) { iterator(int(key.(IntKey)), value) }, true)
+}
+
+func (me *benchmarkArena) WalkRange(iterator func(key int, value interface{}), from int, to int) {
+  me.tree.WalkRange(func(key Comparable, value interface{}) { iterator(int(key.(IntKey)), value) }, IntKey(from), IntKey(to), true)
+}
+
+var arenaBenchmarkImplementations = []benchmarkImplementation{
+  benchmarkImplementations[0],
+  { "ArenaTree", func() benchmarkMap { return &benchmarkArena{ NewArenaTree() } }, func(order string) bool { return order == "Sequential" } },
+}
+
+// Measure the heap held by, and the time a full GC takes with, a live tree of n entries. Values are nil,
+// so that only the trees' own allocations are counted.
+func BenchmarkArenaGC(b *testing.B) {
+  for _, impl := range arenaBenchmarkImplementations {
+    for _, n := range []int{ 100000, 1000000 } {
+      impl, n := impl, n
+      b.Run(fmt.Sprintf("%s/%d", impl.name, n), func(b *testing.B) {
+        keys := benchmarkKeys(n, "Random")
+        runtime.GC()
+        before := &runtime.MemStats{}
+        runtime.ReadMemStats(before)
+        m := impl.new()
+        for _, key := range keys { m.Set(key, nil) }
+        runtime.GC()
+        after := &runtime.MemStats{}
+        runtime.ReadMemStats(after)
+        b.ResetTimer()
+        start := time.Now()
+        for i:=0; i<b.N; i++ { runtime.GC() }
+        b.ReportMetric(float64(time.Since(start).Nanoseconds())/float64(b.N), "gc-ns/op")
+        b.ReportMetric(float64(after.HeapAlloc-before.HeapAlloc)/float64(n), "heap-bytes/entry")
+        b.ReportMetric(float64(after.Mallocs-before.Mallocs)/float64(n), "allocs/entry")
+        runtime.KeepAlive(m)
+      })
+    }
+  }
+}
+
+// Measure building a tree of n entries, including the GC work it causes.
+func BenchmarkArenaSet(b *testing.B) {
+  for _, impl := range arenaBenchmarkImplementations {
+    for _, n := range []int{ 100000, 1000000 } {
+      impl, n := impl, n
+      b.Run(fmt.Sprintf("%s/%d", impl.name, n), func(b *testing.B) {
+        keys := benchmarkKeys(n, "Random")
+        before := &runtime.MemStats{}
+        runtime.ReadMemStats(before)
+        b.ReportAllocs()
+        b.ResetTimer()
+        for i:=0; i<b.N; i++ {
+          m := impl.new()
+          for _, key := range keys { m.Set(key, nil) }
+        }
+        b.StopTimer()
+        after := &runtime.MemStats{}
+        runtime.ReadMemStats(after)
+        b.ReportMetric(float64(after.PauseTotalNs-before.PauseTotalNs)/float64(b.N), "gc-pause-ns/op")
+      })
+    }
+  }
+}
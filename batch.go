@@ -0,0 +1,279 @@
+package binarytree
+
+import(
+  "errors"
+  "fmt"
+  "reflect"
+  "sort"
+)
+
+// ErrNilKey is returned when a nil key is supplied where a key is required.
+var ErrNilKey = errors.New("binarytree: nil key")
+
+// ErrBatchAborted is returned by Tree.Apply when applying a batch panicked, for example in a
+// key's comparison methods. The tree is left exactly as it was before the call.
+var ErrBatchAborted = errors.New("binarytree: batch aborted")
+
+// Batch collects Set and Clear operations to be applied to a Tree together with Tree.Apply.
+// Operations on the same key are applied in the order they were added.
+type Batch struct {
+  ops []batchOp
+}
+
+type batchOp struct {
+  key Comparable
+  value interface{}
+  clear bool
+}
+
+// Return a new empty batch
+func NewBatch() *Batch {
+  return &Batch{ ops: []batchOp{} }
+}
+
+// Add a Set of the supplied key and value to the batch, returning the batch.
+func (me *Batch) Set(key Comparable, value interface{}) *Batch {
+  me.ops = append(me.ops, batchOp{ key: key, value: value })
+  return me
+}
+
+// Add a Clear of the supplied key to the batch, returning the batch.
+func (me *Batch) Clear(key Comparable) *Batch {
+  me.ops = append(me.ops, batchOp{ key: key, clear: true })
+  return me
+}
+
+// Return the number of operations in the batch.
+func (me *Batch) Len() int {
+  return len(me.ops)
+}
+
+// Remove all operations from the batch.
+func (me *Batch) Reset() {
+  me.ops = me.ops[:0]
+}
+
+// Check that every key in the batch is non-nil and, unless the tree has a caller-supplied Comparator,
+// of the same type as the tree's keys.
+func (me *Batch) validate(tree *Tree) error {
+  expected := tree.KeyType()
+  for i, op := range me.ops {
+    if op.key == nil { return fmt.Errorf("binarytree: batch operation %d: %w", i, ErrNilKey) }
+    if tree.compare != nil { continue }
+    actual := reflect.TypeOf(op.key)
+    if expected == nil { expected = actual }
+    if actual != expected {
+      return fmt.Errorf("binarytree: batch operation %d: %w", i, &ErrKeyTypeMismatch{ Expected: expected, Actual: actual })
+    }
+  }
+  return nil
+}
+
+// Apply all the operations in the batch to the tree, or none of them. The batch is validated
+// first, returning an error wrapping ErrNilKey or *ErrKeyTypeMismatch without changing the tree.
+// If applying the batch panics, the tree is left exactly as it was and an error wrapping
+// ErrBatchAborted is returned. Watchers are notified only once the whole batch has been applied.
+//
+// Large batches, relative to the size of the tree, are merged with the tree in one pass and the
+// tree is rebuilt balanced, which is much faster than individual Set calls for keys in order.
+// Batches that are already sorted by key skip sorting.
+func (me *Tree) Apply(batch *Batch) (err error) {
+  if err := batch.validate(me); err != nil { return err }
+  if len(batch.ops) == 0 { return nil }
+  me.expire()
+
+  var events []Event
+  var j *journal
+  defer func() {
+    if r := recover(); r != nil {
+      if j != nil { j.rollback() }
+      err = fmt.Errorf("%w: %v", ErrBatchAborted, r)
+      return
+    }
+    if me.ttl != nil {
+      for _, op := range batch.ops { me.ttl.forget(op.key) }
+    }
+    for _, event := range events { me.emit(event) }
+  }()
+
+  if me.root == nil || me.root.countUpTo(len(batch.ops) * batchRebuildRatio) < len(batch.ops) * batchRebuildRatio {
+    events = me.applyRebuild(batch)
+    me.rehashAll(false)
+    return nil
+  }
+  j = &journal{}
+  events = me.applyJournaled(batch, j)
+  // Every node whose subtree changed is on the search path of one of the keys in the final tree
+  for _, op := range batch.ops { me.rehashPath(op.key, !op.clear) }
+  return nil
+}
+
+// Trees with fewer than this many nodes per batch operation are rebuilt by Apply.
+const batchRebuildRatio = 8
+
+// Return the number of nodes in this node's subtree, counting no further than limit.
+func (me *Node) countUpTo(limit int) int {
+  count := 0
+  me.walk(func(node *Node) bool { count++; return count < limit }, true)
+  return count
+}
+
+// Merge the sorted batch with the tree's nodes in order, then relink the result as a balanced tree.
+// All comparisons are made before the tree is changed.
+func (me *Tree) applyRebuild(batch *Batch) []Event {
+  cmp := me.Comparator()
+  ops := sortedBatchOps(batch.ops, cmp)
+
+  existing := []*Node{}
+  if me.root != nil { me.root.walk(func(node *Node) bool { existing = append(existing, node); return true }, true) }
+
+  nodes := make([]*Node, 0, len(existing) + len(ops))
+  events := []Event{}
+  updates := []*Node{}
+  values := []interface{}{}
+  i, k := 0, 0
+  for i < len(existing) || k < len(ops) {
+    c := -1
+    if i == len(existing) {
+      c = 1
+    } else if k < len(ops) {
+      c = cmp(existing[i].Key, ops[k].key)
+    }
+    switch {
+      case c < 0:
+        nodes = append(nodes, existing[i])
+        i++
+      case c > 0:
+        if !ops[k].clear {
+          nodes = append(nodes, NewNodeKeyValue(ops[k].key, ops[k].value))
+          events = append(events, Event{ Op: EventSet, Key: ops[k].key, NewValue: ops[k].value })
+        }
+        k++
+      default:
+        if ops[k].clear {
+          events = append(events, Event{ Op: EventClear, Key: existing[i].Key, OldValue: existing[i].Value })
+        } else {
+          nodes = append(nodes, existing[i])
+          updates = append(updates, existing[i])
+          values = append(values, ops[k].value)
+          events = append(events, Event{ Op: EventSet, Key: existing[i].Key, OldValue: existing[i].Value, NewValue: ops[k].value })
+        }
+        i++
+        k++
+    }
+  }
+
+  for n, node := range updates {
+    node.Value = values[n]
+    node.hash = nil
+  }
+  me.root = linkBalanced(nodes)
+  return events
+}
+
+// Return the operations sorted by key, keeping only the last operation on each key.
+func sortedBatchOps(ops []batchOp, cmp Comparator) []batchOp {
+  sorted := true
+  for i:=1; i<len(ops); i++ {
+    if cmp(ops[i-1].key, ops[i].key) >= 0 { sorted = false; break }
+  }
+  if sorted { return ops }
+  ops = append([]batchOp{}, ops...)
+  sort.SliceStable(ops, func(i, j int) bool { return cmp(ops[i].key, ops[j].key) < 0 })
+  last := ops[:0]
+  for i, op := range ops {
+    if i+1 < len(ops) && cmp(op.key, ops[i+1].key) == 0 { continue }
+    last = append(last, op)
+  }
+  return last
+}
+
+// Link the supplied nodes, which must be in key order, into a balanced tree and return its root.
+func linkBalanced(nodes []*Node) *Node {
+  if len(nodes) == 0 { return nil }
+  mid := len(nodes) / 2
+  root := nodes[mid]
+  root.Left = linkBalanced(nodes[:mid])
+  root.Right = linkBalanced(nodes[mid+1:])
+  return root
+}
+
+// Apply each operation in turn, recording every change in the journal so it can be rolled back.
+// Removal follows the same rules as Node.Remove.
+func (me *Tree) applyJournaled(batch *Batch, j *journal) []Event {
+  cmp := me.Comparator()
+  events := []Event{}
+  for _, op := range batch.ops {
+    slot := &me.root
+    for *slot != nil {
+      c := cmp(op.key, (*slot).Key)
+      if c == 0 { break }
+      if c < 0 {
+        slot = &(*slot).Left
+      } else {
+        slot = &(*slot).Right
+      }
+    }
+    node := *slot
+    switch {
+      case !op.clear && node == nil:
+        j.link(slot, NewNodeKeyValue(op.key, op.value))
+        events = append(events, Event{ Op: EventSet, Key: op.key, NewValue: op.value })
+      case !op.clear:
+        events = append(events, Event{ Op: EventSet, Key: node.Key, OldValue: node.Value, NewValue: op.value })
+        j.value(node, op.value)
+      case node == nil:
+      case node.Left == nil:
+        j.link(slot, node.Right)
+        events = append(events, Event{ Op: EventClear, Key: node.Key, OldValue: node.Value })
+      case node.Right == nil:
+        j.link(slot, node.Left)
+        events = append(events, Event{ Op: EventClear, Key: node.Key, OldValue: node.Value })
+      default:
+        left, right := node.Left, node.Right
+        j.link(&left.Maximum().Right, right)
+        j.link(&node.Left, nil)
+        j.link(&node.Right, nil)
+        j.link(slot, left)
+        events = append(events, Event{ Op: EventClear, Key: node.Key, OldValue: node.Value })
+    }
+  }
+  return events
+}
+
+// journal records changes to a tree's links and values so they can be undone.
+type journal struct {
+  entries []journalEntry
+}
+
+type journalEntry struct {
+  slot **Node
+  link *Node
+  node *Node
+  value interface{}
+}
+
+// Set *slot to node, recording its previous value.
+func (me *journal) link(slot **Node, node *Node) {
+  me.entries = append(me.entries, journalEntry{ slot: slot, link: *slot })
+  *slot = node
+}
+
+// Set node's value, recording its previous value.
+func (me *journal) value(node *Node, value interface{}) {
+  me.entries = append(me.entries, journalEntry{ node: node, value: node.Value })
+  node.Value = value
+}
+
+// Undo every recorded change, most recent first.
+func (me *journal) rollback() {
+  for i:=len(me.entries)-1; i>=0; i-- {
+    entry := me.entries[i]
+    if entry.slot != nil {
+      *entry.slot = entry.link
+    } else {
+      entry.node.Value = entry.value
+    }
+  }
+  me.entries = nil
+}
@@ -0,0 +1,169 @@
+package binarytree
+
+import (
+  "errors"
+  "fmt"
+  "testing"
+  "github.com/stretchr/testify/assert"
+)
+
+// panicKey is an int key whose comparisons panic when either side is 13
+type panicKey int
+
+func (me panicKey) check(other Comparable) panicKey {
+  otherKey, ok := other.(panicKey)
+  if !ok { return 0 }
+  if me == 13 || otherKey == 13 { panic("unlucky key") }
+  return otherKey
+}
+
+func (me panicKey) EqualTo(other Comparable) bool { return me == me.check(other) }
+func (me panicKey) LessThan(other Comparable) bool { return me < me.check(other) }
+func (me panicKey) GreaterThan(other Comparable) bool { return me > me.check(other) }
+func (me panicKey) ValueOf() interface{} { return int(me) }
+
+func batchTreeKeys(tree *Tree) []Comparable {
+  keys := []Comparable{}
+  tree.Walk(func(key Comparable, value interface{}) { keys = append(keys, key) }, true)
+  return keys
+}
+
+func TestBatchBuilder(t *testing.T) {
+  batch := NewBatch().Set(IntKey(1), "one").Clear(IntKey(2)).Set(IntKey(3), "three")
+  assert.Equal(t, 3, batch.Len())
+  batch.Reset()
+  assert.Equal(t, 0, batch.Len())
+}
+
+func TestApplyEmptyTree(t *testing.T) {
+  tree := NewTree()
+  sub := tree.Watch(nil, nil, &WatchOptions{ Buffer: 10 })
+  batch := NewBatch()
+  for i:=1; i<=7; i++ { batch.Set(IntKey(i), i) }
+  assert.Nil(t, tree.Apply(batch))
+
+  assert.Equal(t, []Comparable{ IntKey(1), IntKey(2), IntKey(3), IntKey(4), IntKey(5), IntKey(6), IntKey(7) }, batchTreeKeys(tree))
+  // Sorted batches are linked into a balanced tree
+  assert.Equal(t, IntKey(4), tree.root.Key)
+  assert.Equal(t, 2, tree.Stats().Height)
+  assert.Nil(t, tree.Validate())
+  assert.Equal(t, 7, len(sub.Events()))
+  assert.Equal(t, Event{ Op: EventSet, Key: IntKey(1), NewValue: 1 }, <-sub.Events())
+}
+
+func TestApplyRebuild(t *testing.T) {
+  tree := NewTree()
+  tree.Set(IntKey(2), "two")
+  tree.Set(IntKey(4), "four")
+  sub := tree.Watch(nil, nil, &WatchOptions{ Buffer: 10 })
+
+  batch := NewBatch().Set(IntKey(5), "five").Clear(IntKey(2)).Set(IntKey(4), "FOUR").Set(IntKey(1), "one").Set(IntKey(5), "FIVE").Clear(IntKey(9))
+  assert.Nil(t, tree.Apply(batch))
+
+  assert.Equal(t, []Comparable{ IntKey(1), IntKey(4), IntKey(5) }, batchTreeKeys(tree))
+  _, value := tree.Get(IntKey(4))
+  assert.Equal(t, "FOUR", value)
+  // The last operation on a key wins
+  _, value = tree.Get(IntKey(5))
+  assert.Equal(t, "FIVE", value)
+  assert.Nil(t, tree.Validate())
+
+  assert.Equal(t, Event{ Op: EventSet, Key: IntKey(1), NewValue: "one" }, <-sub.Events())
+  assert.Equal(t, Event{ Op: EventClear, Key: IntKey(2), OldValue: "two" }, <-sub.Events())
+  assert.Equal(t, Event{ Op: EventSet, Key: IntKey(4), OldValue: "four", NewValue: "FOUR" }, <-sub.Events())
+  assert.Equal(t, Event{ Op: EventSet, Key: IntKey(5), NewValue: "FIVE" }, <-sub.Events())
+  assert.Equal(t, 0, len(sub.Events()))
+}
+
+func TestApplyIncremental(t *testing.T) {
+  tree := NewTree()
+  for _, key := range []int{ 50, 25, 75, 10, 30, 60, 90, 5, 15, 27, 35, 55, 65, 80, 95, 1, 7, 12 } { tree.Set(IntKey(key), key) }
+  root := tree.root
+  sub := tree.Watch(nil, nil, &WatchOptions{ Buffer: 10 })
+
+  assert.Nil(t, tree.Apply(NewBatch().Clear(IntKey(25)).Set(IntKey(26), 26)))
+
+  // Small batches are applied in place
+  assert.Equal(t, root, tree.root)
+  assert.Equal(t, []Comparable{ IntKey(1), IntKey(5), IntKey(7), IntKey(10), IntKey(12), IntKey(15), IntKey(26), IntKey(27), IntKey(30), IntKey(35), IntKey(50), IntKey(55), IntKey(60), IntKey(65), IntKey(75), IntKey(80), IntKey(90), IntKey(95) }, batchTreeKeys(tree))
+  assert.Nil(t, tree.Validate())
+  assert.Equal(t, Event{ Op: EventClear, Key: IntKey(25), OldValue: 25 }, <-sub.Events())
+  assert.Equal(t, Event{ Op: EventSet, Key: IntKey(26), NewValue: 26 }, <-sub.Events())
+}
+
+func TestApplyValidation(t *testing.T) {
+  tree := NewTree()
+  tree.Set(IntKey(1), "one")
+
+  err := tree.Apply(NewBatch().Set(IntKey(2), "two").Set(nil, "nil"))
+  assert.True(t, errors.Is(err, ErrNilKey))
+  assert.Equal(t, "binarytree: batch operation 1: binarytree: nil key", err.Error())
+
+  err = tree.Apply(NewBatch().Set(IntKey(2), "two").Clear(StringKey("three")))
+  var mismatch *ErrKeyTypeMismatch
+  assert.True(t, errors.As(err, &mismatch))
+
+  // Keys in a batch for an empty tree must agree with each other
+  err = NewTree().Apply(NewBatch().Set(StringKey("a"), 1).Set(IntKey(2), 2))
+  assert.True(t, errors.As(err, &mismatch))
+
+  // Nothing was applied
+  assert.Equal(t, []Comparable{ IntKey(1) }, batchTreeKeys(tree))
+  assert.Nil(t, tree.Apply(NewBatch()))
+}
+
+func TestApplyRollback(t *testing.T) {
+  for _, size := range []int{ 4, 100 } {
+    tree := NewTree()
+    for i:=0; i<size; i++ { key := (i*37)%size + 20; tree.Set(panicKey(key), key) }
+    if size == 4 { tree.Set(panicKey(5), "five") }
+    before := tree.String()
+    sub := tree.Watch(nil, nil, &WatchOptions{ Buffer: 10 })
+
+    // The panic is raised after other operations have been applied
+    batch := NewBatch().Clear(panicKey(20)).Set(panicKey(21), "changed").Set(panicKey(1), "new").Clear(panicKey(5)).Set(panicKey(13), "unlucky")
+    err := tree.Apply(batch)
+    assert.True(t, errors.Is(err, ErrBatchAborted), size)
+    assert.Equal(t, "binarytree: batch aborted: unlucky key", err.Error(), size)
+
+    assert.Equal(t, before, tree.String(), size)
+    _, value := tree.Get(panicKey(21))
+    assert.Equal(t, 21, value, size)
+    assert.Nil(t, tree.Validate(), size)
+    assert.Equal(t, 0, len(sub.Events()), size)
+  }
+}
+
+func TestSortedBatchOps(t *testing.T) {
+  ops := []batchOp{ { key: IntKey(1) }, { key: IntKey(2) } }
+  sorted := sortedBatchOps(ops, DefaultComparator)
+  assert.Equal(t, &ops[0], &sorted[0])
+
+  ops = []batchOp{ { key: IntKey(2), value: "a" }, { key: IntKey(1) }, { key: IntKey(2), value: "b" }, { key: IntKey(2), clear: true }, { key: IntKey(3) } }
+  sorted = sortedBatchOps(ops, DefaultComparator)
+  assert.Equal(t, []batchOp{ { key: IntKey(1) }, { key: IntKey(2), clear: true }, { key: IntKey(3) } }, sorted)
+  // The batch itself is not reordered
+  assert.Equal(t, IntKey(2), ops[0].key)
+}
+
+func BenchmarkApplySorted(b *testing.B) {
+  for _, n := range []int{ 1000, 100000 } {
+    n := n
+    b.Run(fmt.Sprintf("Apply/%d", n), func(b *testing.B) {
+      batch := NewBatch()
+      for _, key := range benchmarkKeys(n, "Sequential") { batch.Set(IntKey(key), key) }
+      b.ReportAllocs()
+      b.ResetTimer()
+      for i:=0; i<b.N; i++ { NewTree().Apply(batch) }
+    })
+    b.Run(fmt.Sprintf("Set/%d", n), func(b *testing.B) {
+      keys := benchmarkKeys(n, "Random")
+      b.ReportAllocs()
+      b.ResetTimer()
+      for i:=0; i<b.N; i++ {
+        tree := NewTree()
+        for _, key := range keys { tree.Set(IntKey(key), key) }
+      }
+    })
+  }
+}
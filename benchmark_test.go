@@ -0,0 +1,302 @@
+package binarytree
+
+import (
+  "fmt"
+  "math/rand"
+  "sort"
+  "testing"
+  "github.com/stretchr/testify/assert"
+)
+
+// Run with: go test -run XXX -bench . -benchmem
+
+var benchmarkSizes = []int{ 1000, 100000, 1000000 }
+
+// Structures that take quadratic time to build in the given order are skipped above this size.
+const benchmarkQuadraticLimit = 10000
+
+// benchmarkMap is the interface each benchmarked structure is adapted to.
+type benchmarkMap interface {
+  Set(key int, value interface{})
+  Get(key int) (bool, interface{})
+  Clear(key int)
+  Next(key int) (bool, int)
+  Walk(iterator func(key int, value interface{}))
+  WalkRange(iterator func(key int, value interface{}), from int, to int)
+}
+
+type benchmarkImplementation struct {
+  name string
+  new func() benchmarkMap
+  // Return true if inserting keys in this order takes quadratic time
+  quadratic func(order string) bool
+}
+
+var benchmarkImplementations = []benchmarkImplementation{
+  { "Tree", func() benchmarkMap { return &benchmarkTree{ NewTree() } }, func(order string) bool { return order == "Sequential" } },
+  { "SortedSlice", func() benchmarkMap { return &sortedSliceMap{} }, func(order string) bool { return order == "Random" } },
+  { "MapSort", func() benchmarkMap { return &mapSortMap{ values: map[int]interface{}{} } }, func(order string) bool { return false } },
+}
+
+// benchmarkTree adapts Tree to benchmarkMap
+type benchmarkTree struct {
+  tree *Tree
+}
+
+func (me *benchmarkTree) Set(key int, value interface{}) { me.tree.Set(IntKey(key), value) }
+func (me *benchmarkTree) Get(key int) (bool, interface{}) { return me.tree.Get(IntKey(key)) }
+func (me *benchmarkTree) Clear(key int) { me.tree.Clear(IntKey(key)) }
+
+func (me *benchmarkTree) Next(key int) (bool, int) {
+  found, next, _ := me.tree.Next(IntKey(key))
+  if !found { return false, 0 }
+  return true, int(next.(IntKey))
+}
+
+func (me *benchmarkTree) Walk(iterator func(key int, value interface{})) {
+  me.tree.Walk(func(key Comparable, value interface{}) { iterator(int(key.(IntKey)), value) }, true)
+}
+
+func (me *benchmarkTree) WalkRange(iterator func(key int, value interface{}), from int, to int) {
+  me.tree.WalkRange(func(key Comparable, value interface{}) { iterator(int(key.(IntKey)), value) }, IntKey(from), IntKey(to), true)
+}
+
+// sortedSliceMap is a reference ordered map using a sorted slice and binary search
+type sortedSliceMap struct {
+  keys []int
+  values []interface{}
+}
+
+func (me *sortedSliceMap) Set(key int, value interface{}) {
+  i := sort.SearchInts(me.keys, key)
+  if i < len(me.keys) && me.keys[i] == key {
+    me.values[i] = value
+    return
+  }
+  me.keys = append(me.keys, 0)
+  copy(me.keys[i+1:], me.keys[i:])
+  me.keys[i] = key
+  me.values = append(me.values, nil)
+  copy(me.values[i+1:], me.values[i:])
+  me.values[i] = value
+}
+
+func (me *sortedSliceMap) Get(key int) (bool, interface{}) {
+  i := sort.SearchInts(me.keys, key)
+  if i < len(me.keys) && me.keys[i] == key { return true, me.values[i] }
+  return false, nil
+}
+
+func (me *sortedSliceMap) Clear(key int) {
+  i := sort.SearchInts(me.keys, key)
+  if i == len(me.keys) || me.keys[i] != key { return }
+  me.keys = append(me.keys[:i], me.keys[i+1:]...)
+  me.values = append(me.values[:i], me.values[i+1:]...)
+}
+
+func (me *sortedSliceMap) Next(key int) (bool, int) {
+  i := sort.SearchInts(me.keys, key+1)
+  if i == len(me.keys) { return false, 0 }
+  return true, me.keys[i]
+}
+
+func (me *sortedSliceMap) Walk(iterator func(key int, value interface{})) {
+  for i, key := range me.keys { iterator(key, me.values[i]) }
+}
+
+func (me *sortedSliceMap) WalkRange(iterator func(key int, value interface{}), from int, to int) {
+  for i:=sort.SearchInts(me.keys, from); i<len(me.keys) && me.keys[i]<=to; i++ { iterator(me.keys[i], me.values[i]) }
+}
+
+// mapSortMap is a reference ordered map using a map, sorting its keys on every ordered operation
+type mapSortMap struct {
+  values map[int]interface{}
+}
+
+func (me *mapSortMap) Set(key int, value interface{}) { me.values[key] = value }
+func (me *mapSortMap) Clear(key int) { delete(me.values, key) }
+
+func (me *mapSortMap) Get(key int) (bool, interface{}) {
+  value, found := me.values[key]
+  return found, value
+}
+
+func (me *mapSortMap) sortedKeys() []int {
+  keys := make([]int, 0, len(me.values))
+  for key := range me.values { keys = append(keys, key) }
+  sort.Ints(keys)
+  return keys
+}
+
+func (me *mapSortMap) Next(key int) (bool, int) {
+  found, next := false, 0
+  for candidate := range me.values {
+    if candidate > key && (!found || candidate < next) { found, next = true, candidate }
+  }
+  return found, next
+}
+
+func (me *mapSortMap) Walk(iterator func(key int, value interface{})) {
+  for _, key := range me.sortedKeys() { iterator(key, me.values[key]) }
+}
+
+func (me *mapSortMap) WalkRange(iterator func(key int, value interface{}), from int, to int) {
+  keys := []int{}
+  for key := range me.values {
+    if key >= from && key <= to { keys = append(keys, key) }
+  }
+  sort.Ints(keys)
+  for _, key := range keys { iterator(key, me.values[key]) }
+}
+
+// Helpers
+
+func benchmarkKeys(n int, order string) []int {
+  return getTestKeys(n, order == "Random")
+}
+
+// Return a structure holding the keys 0..n-1, inserted in random order so that a Tree is not degenerate.
+func benchmarkPopulated(impl benchmarkImplementation, n int) benchmarkMap {
+  m := impl.new()
+  if impl.name == "SortedSlice" {
+    for _, key := range benchmarkKeys(n, "Sequential") { m.Set(key, key) }
+    return m
+  }
+  for _, key := range benchmarkKeys(n, "Random") { m.Set(key, key) }
+  return m
+}
+
+func benchmarkEach(b *testing.B, fn func(b *testing.B, impl benchmarkImplementation, n int)) {
+  for _, impl := range benchmarkImplementations {
+    for _, n := range benchmarkSizes {
+      impl, n := impl, n
+      b.Run(fmt.Sprintf("%s/%d", impl.name, n), func(b *testing.B) { fn(b, impl, n) })
+    }
+  }
+}
+
+// Benchmarks
+
+func BenchmarkSet(b *testing.B) {
+  for _, order := range []string{ "Sequential", "Random" } {
+    for _, impl := range benchmarkImplementations {
+      for _, n := range benchmarkSizes {
+        order, impl, n := order, impl, n
+        b.Run(fmt.Sprintf("%s/%s/%d", order, impl.name, n), func(b *testing.B) {
+          if impl.quadratic(order) && n > benchmarkQuadraticLimit { b.Skipf("%s inserts into %s take quadratic time", order, impl.name) }
+          keys := benchmarkKeys(n, order)
+          b.ReportAllocs()
+          b.ResetTimer()
+          for i:=0; i<b.N; i++ {
+            m := impl.new()
+            for _, key := range keys { m.Set(key, key) }
+          }
+          b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N*n), "ns/key")
+        })
+      }
+    }
+  }
+}
+
+func BenchmarkGet(b *testing.B) {
+  benchmarkEach(b, func(b *testing.B, impl benchmarkImplementation, n int) {
+    m := benchmarkPopulated(impl, n)
+    keys := benchmarkKeys(n, "Random")
+    b.ReportAllocs()
+    b.ResetTimer()
+    for i:=0; i<b.N; i++ { m.Get(keys[i%n]) }
+  })
+}
+
+// Each op clears a key and sets it again, so that the size of the structure stays the same.
+func BenchmarkClear(b *testing.B) {
+  benchmarkEach(b, func(b *testing.B, impl benchmarkImplementation, n int) {
+    m := benchmarkPopulated(impl, n)
+    keys := benchmarkKeys(n, "Random")
+    b.ReportAllocs()
+    b.ResetTimer()
+    for i:=0; i<b.N; i++ {
+      key := keys[i%n]
+      m.Clear(key)
+      m.Set(key, key)
+    }
+  })
+}
+
+func BenchmarkNext(b *testing.B) {
+  benchmarkEach(b, func(b *testing.B, impl benchmarkImplementation, n int) {
+    if impl.name == "MapSort" && n > benchmarkQuadraticLimit { b.Skipf("stepping through %s takes quadratic time", impl.name) }
+    m := benchmarkPopulated(impl, n)
+    b.ReportAllocs()
+    b.ResetTimer()
+    key := -1
+    for i:=0; i<b.N; i++ {
+      found, next := m.Next(key)
+      if !found { next = -1 }
+      key = next
+    }
+  })
+}
+
+func BenchmarkWalk(b *testing.B) {
+  benchmarkEach(b, func(b *testing.B, impl benchmarkImplementation, n int) {
+    m := benchmarkPopulated(impl, n)
+    count := 0
+    b.ReportAllocs()
+    b.ResetTimer()
+    for i:=0; i<b.N; i++ { m.Walk(func(key int, value interface{}) { count++ }) }
+  })
+}
+
+func BenchmarkWalkRange(b *testing.B) {
+  for _, width := range []string{ "Narrow", "Wide" } {
+    width := width
+    b.Run(width, func(b *testing.B) {
+      benchmarkEach(b, func(b *testing.B, impl benchmarkImplementation, n int) {
+        m := benchmarkPopulated(impl, n)
+        // Narrow ranges hold 100 keys, wide ranges a tenth of the keys
+        span := 100
+        if width == "Wide" { span = n / 10 }
+        r := rand.New(rand.NewSource(int64(n)))
+        count := 0
+        b.ReportAllocs()
+        b.ResetTimer()
+        for i:=0; i<b.N; i++ {
+          from := r.Intn(n - span)
+          m.WalkRange(func(key int, value interface{}) { count++ }, from, from+span-1)
+        }
+      })
+    })
+  }
+}
+
+func TestBenchmarkImplementations(t *testing.T) {
+  // The reference implementations must agree with Tree for the benchmarks to be meaningful
+  for _, impl := range benchmarkImplementations {
+    m := impl.new()
+    for _, key := range benchmarkKeys(100, "Random") { m.Set(key, key*2) }
+    m.Clear(50)
+    m.Set(10, "ten")
+
+    found, value := m.Get(10)
+    assert.True(t, found, impl.name)
+    assert.Equal(t, "ten", value, impl.name)
+    found, _ = m.Get(50)
+    assert.False(t, found, impl.name)
+
+    found, next := m.Next(49)
+    assert.True(t, found, impl.name)
+    assert.Equal(t, 51, next, impl.name)
+    found, _ = m.Next(99)
+    assert.False(t, found, impl.name)
+
+    keys := []int{}
+    m.Walk(func(key int, value interface{}) { keys = append(keys, key) })
+    assert.Equal(t, 99, len(keys), impl.name)
+    assert.True(t, sort.IntsAreSorted(keys), impl.name)
+
+    keys = []int{}
+    m.WalkRange(func(key int, value interface{}) { keys = append(keys, key) }, 48, 52)
+    assert.Equal(t, []int{48,49,51,52}, keys, impl.name)
+  }
+}
@@ -0,0 +1,179 @@
+package binarytree
+
+// BoundedOptions configures a BoundedTree. A zero limit is unlimited.
+type BoundedOptions struct {
+  // The maximum number of entries.
+  MaxEntries int
+  // The maximum total size of the entries, as measured by Size.
+  MaxBytes int64
+  // Return the size of an entry. Required if MaxBytes is set.
+  Size func(key Comparable, value interface{}) int64
+  // Choose which entry to evict. Defaults to NewLRUPolicy().
+  Policy EvictionPolicy
+  // If set, called with the key and value of each evicted entry.
+  OnEvict func(key Comparable, value interface{})
+  // Order keys with this Comparator. Defaults to DefaultComparator.
+  Comparator Comparator
+}
+
+// BoundedCounters holds the cache counters of a BoundedTree
+type BoundedCounters struct {
+  Hits uint64
+  Misses uint64
+  Evictions uint64
+}
+
+// BoundedTree is an ordered map that evicts entries to stay within a maximum number of entries or
+// total size. Get counts as an access for the eviction policy; the ordered methods Next, Previous,
+// First, Last, Walk and WalkRange do not.
+type BoundedTree struct {
+  tree *Tree
+  opts BoundedOptions
+  count int
+  bytes int64
+  counters BoundedCounters
+}
+
+// boundedEntry is the value stored in a BoundedTree's underlying tree
+type boundedEntry struct {
+  value interface{}
+  size int64
+}
+
+// Return a new empty BoundedTree with the supplied options.
+func NewBoundedTree(opts BoundedOptions) *BoundedTree {
+  if opts.Comparator == nil { opts.Comparator = DefaultComparator }
+  if opts.Policy == nil { opts.Policy = NewLRUPolicy() }
+  if setter, ok := opts.Policy.(comparatorSetter); ok { setter.setComparator(opts.Comparator) }
+  if opts.MaxBytes > 0 && opts.Size == nil { panic("binarytree: BoundedOptions.MaxBytes requires Size") }
+  return &BoundedTree{ tree: NewTreeWithComparator(opts.Comparator), opts: opts }
+}
+
+// Add the supplied key and value to the tree, then evict entries until the tree is within its limits.
+// If the key already exists, the value will be overwritten. The new entry may itself be evicted,
+// for example if it alone is larger than MaxBytes.
+func (me *BoundedTree) Set(key Comparable, value interface{}) {
+  var size int64
+  if me.opts.Size != nil { size = me.opts.Size(key, value) }
+  if node := me.tree.GetNode(key); node != nil {
+    me.bytes += size - node.Value.(*boundedEntry).size
+    node.Value = &boundedEntry{ value: value, size: size }
+    me.opts.Policy.Accessed(key)
+  } else {
+    me.tree.Set(key, &boundedEntry{ value: value, size: size })
+    me.count++
+    me.bytes += size
+    me.opts.Policy.Added(key)
+  }
+  me.evict()
+}
+
+// Get the value associated with the supplied key, counting a hit or a miss. Return (true, value) if found,
+// (false, nil) if not.
+func (me *BoundedTree) Get(key Comparable) (bool, interface{}) {
+  node := me.tree.GetNode(key)
+  if node == nil {
+    me.counters.Misses++
+    return false, nil
+  }
+  me.counters.Hits++
+  me.opts.Policy.Accessed(key)
+  return true, node.Value.(*boundedEntry).value
+}
+
+// Clear (Delete) the supplied key
+func (me *BoundedTree) Clear(key Comparable) {
+  node := me.tree.GetNode(key)
+  if node == nil { return }
+  me.count--
+  me.bytes -= node.Value.(*boundedEntry).size
+  me.tree.Clear(key)
+  me.opts.Policy.Removed(key)
+}
+
+// Evict entries until the tree is within its limits.
+func (me *BoundedTree) evict() {
+  for me.over() {
+    found, key := me.opts.Policy.Victim(me.tree)
+    if !found { return }
+    node := me.tree.GetNode(key)
+    if node == nil {
+      // The policy has lost track of the tree; forget the key so that it is not chosen again
+      me.opts.Policy.Removed(key)
+      continue
+    }
+    entry := node.Value.(*boundedEntry)
+    me.count--
+    me.bytes -= entry.size
+    me.tree.Clear(key)
+    me.opts.Policy.Removed(key)
+    me.counters.Evictions++
+    if me.opts.OnEvict != nil { me.opts.OnEvict(node.Key, entry.value) }
+  }
+}
+
+// Return true if the tree is over either of its limits.
+func (me *BoundedTree) over() bool {
+  if me.opts.MaxEntries > 0 && me.count > me.opts.MaxEntries { return true }
+  return me.opts.MaxBytes > 0 && me.bytes > me.opts.MaxBytes
+}
+
+// Return the number of entries in the tree.
+func (me *BoundedTree) Len() int {
+  return me.count
+}
+
+// Return the total size of the entries in the tree, as measured by BoundedOptions.Size.
+func (me *BoundedTree) Bytes() int64 {
+  return me.bytes
+}
+
+// Return the hit, miss and eviction counters.
+func (me *BoundedTree) Counters() BoundedCounters {
+  return me.counters
+}
+
+// Reset the hit, miss and eviction counters to zero.
+func (me *BoundedTree) ResetCounters() {
+  me.counters = BoundedCounters{}
+}
+
+// Return the key and value with the next smallest key than the supplied key.
+// If a smaller key exists, return (true, key, value), otherwise return (false, nil, nil).
+func (me *BoundedTree) Previous(key Comparable) (bool, Comparable, interface{}) {
+  found, previous, entry := me.tree.Previous(key)
+  if !found { return false, nil, nil }
+  return true, previous, entry.(*boundedEntry).value
+}
+
+// Return the key and value with the next largest key than the supplied key.
+// If a larger key exists, return (true, key, value), otherwise return (false, nil, nil).
+func (me *BoundedTree) Next(key Comparable) (bool, Comparable, interface{}) {
+  found, next, entry := me.tree.Next(key)
+  if !found { return false, nil, nil }
+  return true, next, entry.(*boundedEntry).value
+}
+
+// Return the first (lowest) key and value in the tree, or nil, nil if the tree is empty.
+func (me *BoundedTree) First() (Comparable, interface{}) {
+  key, entry := me.tree.First()
+  if key == nil { return nil, nil }
+  return key, entry.(*boundedEntry).value
+}
+
+// Return the last (highest) key and value in the tree, or nil, nil if the tree is empty.
+func (me *BoundedTree) Last() (Comparable, interface{}) {
+  key, entry := me.tree.Last()
+  if key == nil { return nil, nil }
+  return key, entry.(*boundedEntry).value
+}
+
+// Iterate the tree with the function in the supplied direction
+func (me *BoundedTree) Walk(iterator Iterator, forward bool) {
+  me.tree.Walk(func(key Comparable, entry interface{}) { iterator(key, entry.(*boundedEntry).value) }, forward)
+}
+
+// Iterate the tree for all keys between the two keys, inclusive
+func (me *BoundedTree) WalkRange(iterator Iterator, from Comparable, to Comparable, forward bool) {
+  me.tree.WalkRange(func(key Comparable, entry interface{}) { iterator(key, entry.(*boundedEntry).value) }, from, to, forward)
+}
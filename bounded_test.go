@@ -0,0 +1,127 @@
+package binarytree
+
+import (
+  "testing"
+  "github.com/stretchr/testify/assert"
+)
+
+func boundedKeys(tree *BoundedTree) []Comparable {
+  keys := []Comparable{}
+  tree.Walk(func(key Comparable, value interface{}) { keys = append(keys, key) }, true)
+  return keys
+}
+
+func TestBoundedTreeMaxEntries(t *testing.T) {
+  evicted := []Comparable{}
+  tree := NewBoundedTree(BoundedOptions{ MaxEntries: 3, OnEvict: func(key Comparable, value interface{}) { evicted = append(evicted, key) } })
+  for i:=1; i<=3; i++ { tree.Set(IntKey(i), i) }
+  tree.Get(IntKey(1))
+  tree.Set(IntKey(4), 4)
+  tree.Set(IntKey(3), "three")
+  tree.Set(IntKey(5), 5)
+
+  // LRU by default: 2 then 1 were least recently used
+  assert.Equal(t, []Comparable{ IntKey(2), IntKey(1) }, evicted)
+  assert.Equal(t, []Comparable{ IntKey(3), IntKey(4), IntKey(5) }, boundedKeys(tree))
+  assert.Equal(t, 3, tree.Len())
+  assert.Equal(t, uint64(2), tree.Counters().Evictions)
+}
+
+func TestBoundedTreeMaxBytes(t *testing.T) {
+  size := func(key Comparable, value interface{}) int64 { return int64(len(value.(string))) }
+  tree := NewBoundedTree(BoundedOptions{ MaxBytes: 10, Size: size, Policy: NewLowestKeyPolicy() })
+  tree.Set(IntKey(1), "aaaa")
+  tree.Set(IntKey(2), "bbbb")
+  assert.Equal(t, int64(8), tree.Bytes())
+
+  tree.Set(IntKey(3), "cc")
+  assert.Equal(t, int64(10), tree.Bytes())
+  tree.Set(IntKey(3), "cccc")
+  assert.Equal(t, []Comparable{ IntKey(2), IntKey(3) }, boundedKeys(tree))
+  assert.Equal(t, int64(8), tree.Bytes())
+
+  // An entry larger than the limit evicts everything, including itself
+  tree.Set(IntKey(4), "dddddddddddd")
+  assert.Equal(t, []Comparable{}, boundedKeys(tree))
+  assert.Equal(t, int64(0), tree.Bytes())
+  assert.Equal(t, 0, tree.Len())
+
+  tree.Set(IntKey(5), "ee")
+  tree.Clear(IntKey(5))
+  tree.Clear(IntKey(6))
+  assert.Equal(t, int64(0), tree.Bytes())
+
+  assert.Panics(t, func() { NewBoundedTree(BoundedOptions{ MaxBytes: 10 }) })
+}
+
+func TestBoundedTreePolicies(t *testing.T) {
+  for _, test := range []struct{ name string; policy EvictionPolicy; expected []Comparable }{
+    { "LRU", NewLRUPolicy(), []Comparable{ IntKey(1), IntKey(3), IntKey(4) } },
+    // The new key has been used least often, so LFU evicts it
+    { "LFU", NewLFUPolicy(), []Comparable{ IntKey(1), IntKey(2), IntKey(3) } },
+    { "Lowest", NewLowestKeyPolicy(), []Comparable{ IntKey(2), IntKey(3), IntKey(4) } },
+    { "Highest", NewHighestKeyPolicy(), []Comparable{ IntKey(1), IntKey(2), IntKey(3) } },
+  } {
+    tree := NewBoundedTree(BoundedOptions{ MaxEntries: 3, Policy: test.policy })
+    for i:=1; i<=3; i++ { tree.Set(IntKey(i), i) }
+    tree.Get(IntKey(1))
+    tree.Get(IntKey(2))
+    tree.Get(IntKey(1))
+    tree.Get(IntKey(3))
+    tree.Set(IntKey(4), 4)
+    assert.Equal(t, test.expected, boundedKeys(tree), test.name)
+  }
+}
+
+func TestBoundedTreeComparator(t *testing.T) {
+  tree := NewBoundedTree(BoundedOptions{ MaxEntries: 2, Policy: NewLowestKeyPolicy(), Comparator: ReverseComparator(DefaultComparator) })
+  for i:=1; i<=3; i++ { tree.Set(IntKey(i), i) }
+  // Lowest in the tree's order is the largest int
+  assert.Equal(t, []Comparable{ IntKey(2), IntKey(1) }, boundedKeys(tree))
+}
+
+func TestBoundedTreeCounters(t *testing.T) {
+  tree := NewBoundedTree(BoundedOptions{})
+  tree.Set(IntKey(1), "one")
+  found, value := tree.Get(IntKey(1))
+  assert.True(t, found)
+  assert.Equal(t, "one", value)
+  tree.Get(IntKey(1))
+  found, _ = tree.Get(IntKey(2))
+  assert.False(t, found)
+  assert.Equal(t, BoundedCounters{ Hits: 2, Misses: 1 }, tree.Counters())
+  tree.ResetCounters()
+  assert.Equal(t, BoundedCounters{}, tree.Counters())
+}
+
+func TestBoundedTreeOrdered(t *testing.T) {
+  tree := NewBoundedTree(BoundedOptions{ MaxEntries: 10 })
+  first, _ := tree.First()
+  assert.Nil(t, first)
+  for _, key := range []int{ 4, 2, 6, 1, 3, 5, 7 } { tree.Set(IntKey(key), key*10) }
+
+  found, key, value := tree.Next(IntKey(4))
+  assert.True(t, found)
+  assert.Equal(t, IntKey(5), key)
+  assert.Equal(t, 50, value)
+  found, key, value = tree.Previous(IntKey(4))
+  assert.Equal(t, IntKey(3), key)
+  assert.Equal(t, 30, value)
+  found, _, _ = tree.Next(IntKey(7))
+  assert.False(t, found)
+  found, _, _ = tree.Previous(IntKey(1))
+  assert.False(t, found)
+
+  first, value = tree.First()
+  assert.Equal(t, IntKey(1), first)
+  assert.Equal(t, 10, value)
+  last, value := tree.Last()
+  assert.Equal(t, IntKey(7), last)
+  assert.Equal(t, 70, value)
+
+  values := []interface{}{}
+  tree.WalkRange(func(key Comparable, value interface{}) { values = append(values, value) }, IntKey(2), IntKey(4), false)
+  assert.Equal(t, []interface{}{ 40, 30, 20 }, values)
+  // Ordered reads are not counted
+  assert.Equal(t, BoundedCounters{}, tree.Counters())
+}
@@ -2,11 +2,20 @@ package binarytree
 
 import(
   "bytes"
+  "encoding/hex"
+  "errors"
+  "math"
+  "strings"
+  "time"
 )
 // Comparable is an interface for comparable types. All keys used in this implementation of
 // a binary tree must implement this interface.
 //
-// Helper types are supplied for some primitives, please see `IntKey`, [StringKey] and [ByteSliceKey]
+// The built-in key types never panic when compared with a key of a different type; all three
+// comparisons return false. Use Tree.SetChecked and Tree.GetChecked to reject such keys.
+//
+// Helper types are supplied for some primitives, please see [IntKey], [Int64Key], [Uint64Key], [Float64Key],
+// [StringKey], [ByteSliceKey], [LexicalByteSliceKey], [TimeKey] and [UUIDKey]
 type Comparable interface {
   LessThan(Comparable) bool
   EqualTo(Comparable) bool
@@ -14,22 +23,47 @@ type Comparable interface {
   ValueOf() interface{}
 }
 
+// Comparator is a func that orders two keys, returning a negative number if a is less than b,
+// zero if they are equal and a positive number if a is greater than b.
+//
+// A Tree created with NewTreeWithComparator uses its Comparator in place of the
+// LessThan/EqualTo/GreaterThan methods of its keys.
+type Comparator func(a, b Comparable) int
+
+// DefaultComparator orders keys using their own Comparable methods.
+func DefaultComparator(a, b Comparable) int {
+  if a.EqualTo(b) { return 0 }
+  if a.LessThan(b) { return -1 }
+  return 1
+}
+
+// Return a Comparator that orders keys in the reverse order of the supplied Comparator.
+func ReverseComparator(cmp Comparator) Comparator {
+  return func(a, b Comparable) int { return cmp(b, a) }
+}
+
 // IntKey is a type of base type int that implements the Comparable interface.
 type IntKey int
 
 // Return true if this key is less than the supplied IntKey.
 func (me IntKey) LessThan(other Comparable) bool {
-  return me < other.(IntKey)
+  otherKey, ok := other.(IntKey)
+  if !ok { return false }
+  return me < otherKey
 } 
 
 // Return true if this key is equal to the supplied IntKey.
 func (me IntKey) EqualTo(other Comparable) bool {
-  return me == other.(IntKey)
+  otherKey, ok := other.(IntKey)
+  if !ok { return false }
+  return me == otherKey
 } 
 
 // Return true if this key is greater than the supplied IntKey.
 func (me IntKey) GreaterThan(other Comparable) bool {
-  return me > other.(IntKey)
+  otherKey, ok := other.(IntKey)
+  if !ok { return false }
+  return me > otherKey
 } 
 
 // Return the int value as an interface
@@ -40,19 +74,32 @@ func (me IntKey) ValueOf() interface{} {
 // StringKey is a type of base type String that implements the Comparable interface.
 type StringKey string
 
+// Prefixable is implemented by keys that can be matched by prefix. Keys sharing a prefix
+// must be contiguous in the key order, as they are for lexicographically ordered keys.
+type Prefixable interface {
+  Comparable
+  HasPrefix(prefix Comparable) bool
+}
+
 // Return true if this key is less than the supplied StringKey.
 func (me StringKey) LessThan(other Comparable) bool {
-  return me < other.(StringKey)
+  otherKey, ok := other.(StringKey)
+  if !ok { return false }
+  return me < otherKey
 } 
 
 // Return true if this key is equal to the supplied StringKey.
 func (me StringKey) EqualTo(other Comparable) bool {
-  return me == other.(StringKey)
+  otherKey, ok := other.(StringKey)
+  if !ok { return false }
+  return me == otherKey
 } 
 
 // Return true if this key is greater than the supplied StringKey.
 func (me StringKey) GreaterThan(other Comparable) bool {
-  return me > other.(StringKey)
+  otherKey, ok := other.(StringKey)
+  if !ok { return false }
+  return me > otherKey
 } 
 
 // Return the string value as an interface
@@ -60,29 +107,46 @@ func (me StringKey) ValueOf() interface{} {
   return string(me)
 }
 
-// StringKey is a type of base type String that implements the Comparable interface.
+// Return true if this key begins with the supplied StringKey.
+func (me StringKey) HasPrefix(prefix Comparable) bool {
+  otherKey, ok := prefix.(StringKey)
+  if !ok { return false }
+  return strings.HasPrefix(string(me), string(otherKey))
+}
+
+// ByteSliceKey is a type of base type []byte that implements the Comparable interface.
+//
+// ByteSliceKey orders shorter slices before longer ones, and only compares bytes between
+// slices of equal length, so {0x02} sorts before {0x01, 0x00}. Use LexicalByteSliceKey
+// where keys must sort the same way as bytes.Compare.
 type ByteSliceKey []byte
 
 // Return true if this key is less than the supplied ByteSliceKey.
 func (me ByteSliceKey) LessThan(other Comparable) bool {
-  if len(me) > len(other.(ByteSliceKey)) { return false }
-  if len(me) < len(other.(ByteSliceKey)) { return true }
+  otherKey, ok := other.(ByteSliceKey)
+  if !ok { return false }
+  if len(me) > len(otherKey) { return false }
+  if len(me) < len(otherKey) { return true }
 
-  return bytes.Compare(me.ValueOf().([]byte), other.ValueOf().([]byte)) < 0
+  return bytes.Compare(me, otherKey) < 0
 } 
 
 // Return true if this key is equal to the supplied ByteSliceKey.
 func (me ByteSliceKey) EqualTo(other Comparable) bool {
-  if len(me) != len(other.(ByteSliceKey)) { return false }
-  return bytes.Compare(me.ValueOf().([]byte), other.ValueOf().([]byte)) == 0
+  otherKey, ok := other.(ByteSliceKey)
+  if !ok { return false }
+  if len(me) != len(otherKey) { return false }
+  return bytes.Compare(me, otherKey) == 0
 } 
 
 // Return true if this key is greater than the supplied ByteSliceKey.
 func (me ByteSliceKey) GreaterThan(other Comparable) bool {
-  if len(me) < len(other.(ByteSliceKey)) { return false }
-  if len(me) > len(other.(ByteSliceKey)) { return true }
+  otherKey, ok := other.(ByteSliceKey)
+  if !ok { return false }
+  if len(me) < len(otherKey) { return false }
+  if len(me) > len(otherKey) { return true }
 
-  return bytes.Compare(me.ValueOf().([]byte), other.ValueOf().([]byte)) > 0
+  return bytes.Compare(me, otherKey) > 0
 } 
 
 // Return the []byte value as an interface
@@ -90,3 +154,225 @@ func (me ByteSliceKey) ValueOf() interface{} {
   return []byte(me)
 }
 
+
+// LexicalByteSliceKey is a type of base type []byte that implements the Comparable interface,
+// ordered lexicographically as by bytes.Compare.
+type LexicalByteSliceKey []byte
+
+// Return true if this key is less than the supplied LexicalByteSliceKey.
+func (me LexicalByteSliceKey) LessThan(other Comparable) bool {
+  otherKey, ok := other.(LexicalByteSliceKey)
+  if !ok { return false }
+  return bytes.Compare(me, otherKey) < 0
+}
+
+// Return true if this key is equal to the supplied LexicalByteSliceKey.
+func (me LexicalByteSliceKey) EqualTo(other Comparable) bool {
+  otherKey, ok := other.(LexicalByteSliceKey)
+  if !ok { return false }
+  return bytes.Equal(me, otherKey)
+}
+
+// Return true if this key is greater than the supplied LexicalByteSliceKey.
+func (me LexicalByteSliceKey) GreaterThan(other Comparable) bool {
+  otherKey, ok := other.(LexicalByteSliceKey)
+  if !ok { return false }
+  return bytes.Compare(me, otherKey) > 0
+}
+
+// Return the []byte value as an interface
+func (me LexicalByteSliceKey) ValueOf() interface{} {
+  return []byte(me)
+}
+
+// Return true if this key begins with the supplied LexicalByteSliceKey.
+func (me LexicalByteSliceKey) HasPrefix(prefix Comparable) bool {
+  otherKey, ok := prefix.(LexicalByteSliceKey)
+  if !ok { return false }
+  return bytes.HasPrefix(me, otherKey)
+}
+
+// Int64Key is a type of base type int64 that implements the Comparable interface.
+type Int64Key int64
+
+// Return true if this key is less than the supplied Int64Key.
+func (me Int64Key) LessThan(other Comparable) bool {
+  otherKey, ok := other.(Int64Key)
+  if !ok { return false }
+  return me < otherKey
+}
+
+// Return true if this key is equal to the supplied Int64Key.
+func (me Int64Key) EqualTo(other Comparable) bool {
+  otherKey, ok := other.(Int64Key)
+  if !ok { return false }
+  return me == otherKey
+}
+
+// Return true if this key is greater than the supplied Int64Key.
+func (me Int64Key) GreaterThan(other Comparable) bool {
+  otherKey, ok := other.(Int64Key)
+  if !ok { return false }
+  return me > otherKey
+}
+
+// Return the int64 value as an interface
+func (me Int64Key) ValueOf() interface{} {
+  return int64(me)
+}
+
+// Uint64Key is a type of base type uint64 that implements the Comparable interface.
+type Uint64Key uint64
+
+// Return true if this key is less than the supplied Uint64Key.
+func (me Uint64Key) LessThan(other Comparable) bool {
+  otherKey, ok := other.(Uint64Key)
+  if !ok { return false }
+  return me < otherKey
+}
+
+// Return true if this key is equal to the supplied Uint64Key.
+func (me Uint64Key) EqualTo(other Comparable) bool {
+  otherKey, ok := other.(Uint64Key)
+  if !ok { return false }
+  return me == otherKey
+}
+
+// Return true if this key is greater than the supplied Uint64Key.
+func (me Uint64Key) GreaterThan(other Comparable) bool {
+  otherKey, ok := other.(Uint64Key)
+  if !ok { return false }
+  return me > otherKey
+}
+
+// Return the uint64 value as an interface
+func (me Uint64Key) ValueOf() interface{} {
+  return uint64(me)
+}
+
+// Float64Key is a type of base type float64 that implements the Comparable interface.
+//
+// Float64Keys have a total order, unlike float64 itself: -0 sorts before +0, and all NaNs
+// are equal to each other and sort after +Inf. This is the same order TupleKey uses for floats.
+type Float64Key float64
+
+// Return true if this key is less than the supplied Float64Key.
+func (me Float64Key) LessThan(other Comparable) bool {
+  otherKey, ok := other.(Float64Key)
+  if !ok { return false }
+  return float64Ordered(float64(me)) < float64Ordered(float64(otherKey))
+}
+
+// Return true if this key is equal to the supplied Float64Key.
+func (me Float64Key) EqualTo(other Comparable) bool {
+  otherKey, ok := other.(Float64Key)
+  if !ok { return false }
+  return float64Ordered(float64(me)) == float64Ordered(float64(otherKey))
+}
+
+// Return true if this key is greater than the supplied Float64Key.
+func (me Float64Key) GreaterThan(other Comparable) bool {
+  otherKey, ok := other.(Float64Key)
+  if !ok { return false }
+  return float64Ordered(float64(me)) > float64Ordered(float64(otherKey))
+}
+
+// Return the float64 value as an interface
+func (me Float64Key) ValueOf() interface{} {
+  return float64(me)
+}
+
+// Map a float64 to a uint64 whose unsigned order is the total order of Float64Key.
+func float64Ordered(value float64) uint64 {
+  if math.IsNaN(value) { value = math.NaN() }
+  bits := math.Float64bits(value)
+  if bits & (1 << 63) != 0 { return ^bits }
+  return bits ^ (1 << 63)
+}
+
+// TimeKey is a type wrapping time.Time that implements the Comparable interface.
+//
+// TimeKeys are compared by wall clock only. Monotonic clock readings are ignored, as they are
+// only meaningful within one process and would order keys differently from their wall times.
+type TimeKey struct {
+  time.Time
+}
+
+// Return a new TimeKey for the supplied time, with any monotonic clock reading stripped.
+func NewTimeKey(t time.Time) TimeKey {
+  return TimeKey{ t.Round(0) }
+}
+
+// Return true if this key is less than the supplied TimeKey.
+func (me TimeKey) LessThan(other Comparable) bool {
+  otherKey, ok := other.(TimeKey)
+  if !ok { return false }
+  return me.Time.Round(0).Before(otherKey.Time.Round(0))
+}
+
+// Return true if this key is equal to the supplied TimeKey.
+func (me TimeKey) EqualTo(other Comparable) bool {
+  otherKey, ok := other.(TimeKey)
+  if !ok { return false }
+  return me.Time.Round(0).Equal(otherKey.Time.Round(0))
+}
+
+// Return true if this key is greater than the supplied TimeKey.
+func (me TimeKey) GreaterThan(other Comparable) bool {
+  otherKey, ok := other.(TimeKey)
+  if !ok { return false }
+  return me.Time.Round(0).After(otherKey.Time.Round(0))
+}
+
+// Return the time.Time value as an interface
+func (me TimeKey) ValueOf() interface{} {
+  return me.Time
+}
+
+// UUIDKey is a type of base type [16]byte that implements the Comparable interface,
+// ordered lexicographically by its bytes.
+type UUIDKey [16]byte
+
+// ErrInvalidUUID is returned when a string cannot be parsed as a UUIDKey.
+var ErrInvalidUUID = errors.New("binarytree: invalid UUID")
+
+// Parse a UUID in the canonical form xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx into a UUIDKey.
+func ParseUUIDKey(s string) (UUIDKey, error) {
+  var key UUIDKey
+  if len(s) != 36 || s[8] != '-' || s[13] != '-' || s[18] != '-' || s[23] != '-' { return key, ErrInvalidUUID }
+  digits := s[0:8] + s[9:13] + s[14:18] + s[19:23] + s[24:36]
+  if _, err := hex.Decode(key[:], []byte(digits)); err != nil { return UUIDKey{}, ErrInvalidUUID }
+  return key, nil
+}
+
+// Return true if this key is less than the supplied UUIDKey.
+func (me UUIDKey) LessThan(other Comparable) bool {
+  otherKey, ok := other.(UUIDKey)
+  if !ok { return false }
+  return bytes.Compare(me[:], otherKey[:]) < 0
+}
+
+// Return true if this key is equal to the supplied UUIDKey.
+func (me UUIDKey) EqualTo(other Comparable) bool {
+  otherKey, ok := other.(UUIDKey)
+  if !ok { return false }
+  return me == otherKey
+}
+
+// Return true if this key is greater than the supplied UUIDKey.
+func (me UUIDKey) GreaterThan(other Comparable) bool {
+  otherKey, ok := other.(UUIDKey)
+  if !ok { return false }
+  return bytes.Compare(me[:], otherKey[:]) > 0
+}
+
+// Return the [16]byte value as an interface
+func (me UUIDKey) ValueOf() interface{} {
+  return [16]byte(me)
+}
+
+// Return the UUID in the canonical form xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx
+func (me UUIDKey) String() string {
+  s := hex.EncodeToString(me[:])
+  return s[0:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:32]
+}
@@ -1,7 +1,9 @@
 package binarytree
 
 import (
+  "math"
   "testing"
+  "time"
   "github.com/stretchr/testify/assert"
 )
 
@@ -167,3 +169,317 @@ func TestByteSliceKeyGreaterThan(t *testing.T) {
   assert.False(t, c.GreaterThan(c))
 }
 
+func TestDefaultComparator(t *testing.T) {
+  assert.Equal(t, -1, DefaultComparator(IntKey(1), IntKey(2)))
+  assert.Equal(t, 0, DefaultComparator(IntKey(2), IntKey(2)))
+  assert.Equal(t, 1, DefaultComparator(IntKey(3), IntKey(2)))
+  assert.Equal(t, -1, DefaultComparator(StringKey("a"), StringKey("b")))
+}
+
+func TestReverseComparator(t *testing.T) {
+  cmp := ReverseComparator(DefaultComparator)
+
+  assert.Equal(t, 1, cmp(IntKey(1), IntKey(2)))
+  assert.Equal(t, 0, cmp(IntKey(2), IntKey(2)))
+  assert.Equal(t, -1, cmp(IntKey(3), IntKey(2)))
+}
+
+func TestStringKeyHasPrefix(t *testing.T) {
+  assert.True(t, StringKey("abc").HasPrefix(StringKey("ab")))
+  assert.True(t, StringKey("abc").HasPrefix(StringKey("")))
+  assert.True(t, StringKey("abc").HasPrefix(StringKey("abc")))
+  assert.False(t, StringKey("abc").HasPrefix(StringKey("abcd")))
+  assert.False(t, StringKey("abc").HasPrefix(StringKey("b")))
+}
+
+func TestLexicalByteSliceKeyLessThan(t *testing.T) {
+  a := LexicalByteSliceKey("aa")
+  b := LexicalByteSliceKey("b")
+  c := LexicalByteSliceKey{ 0x00, 0x01 }
+  d := LexicalByteSliceKey{ 0x00, 0x01, 0x00 }
+
+  assert.True(t, a.LessThan(b))
+  assert.False(t, b.LessThan(a))
+  assert.True(t, c.LessThan(d))
+  assert.False(t, d.LessThan(c))
+  assert.False(t, a.LessThan(a))
+}
+
+func TestLexicalByteSliceKeyEqualTo(t *testing.T) {
+  a := LexicalByteSliceKey{ 0xAA, 0xEE }
+  b := LexicalByteSliceKey{ 0xAA, 0xEE }
+  c := LexicalByteSliceKey{ 0xAA, 0xEE, 0x00 }
+
+  assert.True(t, a.EqualTo(b))
+  assert.False(t, a.EqualTo(c))
+  assert.False(t, c.EqualTo(a))
+}
+
+func TestLexicalByteSliceKeyGreaterThan(t *testing.T) {
+  a := LexicalByteSliceKey("aa")
+  b := LexicalByteSliceKey("b")
+
+  assert.True(t, b.GreaterThan(a))
+  assert.False(t, a.GreaterThan(b))
+  assert.False(t, b.GreaterThan(b))
+}
+
+func TestLexicalByteSliceKeyValueOf(t *testing.T) {
+  assert.Equal(t, []byte{ 0x01, 0x02 }, LexicalByteSliceKey{ 0x01, 0x02 }.ValueOf())
+}
+
+func TestLexicalByteSliceKeyHasPrefix(t *testing.T) {
+  key := LexicalByteSliceKey{ 0x01, 0x02, 0x03 }
+
+  assert.True(t, key.HasPrefix(LexicalByteSliceKey{ 0x01, 0x02 }))
+  assert.True(t, key.HasPrefix(LexicalByteSliceKey{}))
+  assert.False(t, key.HasPrefix(LexicalByteSliceKey{ 0x02 }))
+  assert.False(t, key.HasPrefix(LexicalByteSliceKey{ 0x01, 0x02, 0x03, 0x04 }))
+}
+
+func TestInt64KeyLessThan(t *testing.T) {
+  x := Int64Key(1)
+  y := Int64Key(math.MaxInt64)
+  a := Int64Key(math.MinInt64)
+
+  assert.True(t, x.LessThan(y))
+  assert.True(t, a.LessThan(x))
+  assert.False(t, y.LessThan(x))
+  assert.False(t, x.LessThan(x))
+}
+
+func TestInt64KeyEqualTo(t *testing.T) {
+  x := Int64Key(1)
+  y := Int64Key(2)
+  z := Int64Key(2)
+
+  assert.True(t, y.EqualTo(z))
+  assert.False(t, x.EqualTo(y))
+}
+
+func TestInt64KeyGreaterThan(t *testing.T) {
+  x := Int64Key(1)
+  y := Int64Key(math.MaxInt64)
+  a := Int64Key(math.MinInt64)
+
+  assert.True(t, y.GreaterThan(x))
+  assert.True(t, x.GreaterThan(a))
+  assert.False(t, a.GreaterThan(x))
+  assert.False(t, x.GreaterThan(x))
+}
+
+func TestInt64KeyValueOf(t *testing.T) {
+  assert.Equal(t, int64(-7), Int64Key(-7).ValueOf())
+}
+
+func TestUint64KeyLessThan(t *testing.T) {
+  x := Uint64Key(1)
+  y := Uint64Key(math.MaxUint64)
+  a := Uint64Key(0)
+
+  assert.True(t, x.LessThan(y))
+  assert.True(t, a.LessThan(x))
+  assert.False(t, y.LessThan(x))
+  assert.False(t, x.LessThan(x))
+}
+
+func TestUint64KeyEqualTo(t *testing.T) {
+  x := Uint64Key(1)
+  y := Uint64Key(math.MaxUint64)
+  z := Uint64Key(math.MaxUint64)
+
+  assert.True(t, y.EqualTo(z))
+  assert.False(t, x.EqualTo(y))
+}
+
+func TestUint64KeyGreaterThan(t *testing.T) {
+  x := Uint64Key(1)
+  y := Uint64Key(math.MaxUint64)
+
+  assert.True(t, y.GreaterThan(x))
+  assert.False(t, x.GreaterThan(y))
+  assert.False(t, x.GreaterThan(x))
+}
+
+func TestUint64KeyValueOf(t *testing.T) {
+  assert.Equal(t, uint64(7), Uint64Key(7).ValueOf())
+}
+
+func TestFloat64KeyLessThan(t *testing.T) {
+  ordered := []Float64Key{
+    Float64Key(math.Inf(-1)),
+    Float64Key(-math.MaxFloat64),
+    Float64Key(-1),
+    Float64Key(-math.SmallestNonzeroFloat64),
+    Float64Key(math.Copysign(0, -1)),
+    Float64Key(0),
+    Float64Key(math.SmallestNonzeroFloat64),
+    Float64Key(1),
+    Float64Key(math.MaxFloat64),
+    Float64Key(math.Inf(1)),
+    Float64Key(math.NaN()),
+  }
+
+  for i:=1; i<len(ordered); i++ {
+    assert.True(t, ordered[i-1].LessThan(ordered[i]), "%v < %v", ordered[i-1], ordered[i])
+    assert.False(t, ordered[i].LessThan(ordered[i-1]), "%v < %v", ordered[i], ordered[i-1])
+  }
+  assert.False(t, Float64Key(1).LessThan(Float64Key(1)))
+  assert.False(t, Float64Key(math.NaN()).LessThan(Float64Key(math.NaN())))
+}
+
+func TestFloat64KeyEqualTo(t *testing.T) {
+  negativeNaN := Float64Key(math.Float64frombits(math.Float64bits(math.NaN()) | (1 << 63)))
+
+  assert.True(t, Float64Key(1.5).EqualTo(Float64Key(1.5)))
+  assert.True(t, Float64Key(math.NaN()).EqualTo(Float64Key(math.NaN())))
+  assert.True(t, negativeNaN.EqualTo(Float64Key(math.NaN())))
+  assert.False(t, Float64Key(math.Copysign(0, -1)).EqualTo(Float64Key(0)))
+  assert.False(t, Float64Key(1).EqualTo(Float64Key(math.NaN())))
+}
+
+func TestFloat64KeyGreaterThan(t *testing.T) {
+  assert.True(t, Float64Key(math.NaN()).GreaterThan(Float64Key(math.Inf(1))))
+  assert.True(t, Float64Key(0).GreaterThan(Float64Key(math.Copysign(0, -1))))
+  assert.True(t, Float64Key(-1).GreaterThan(Float64Key(-2)))
+  assert.False(t, Float64Key(-2).GreaterThan(Float64Key(-1)))
+  assert.False(t, Float64Key(math.NaN()).GreaterThan(Float64Key(math.NaN())))
+}
+
+func TestFloat64KeyValueOf(t *testing.T) {
+  assert.Equal(t, 1.5, Float64Key(1.5).ValueOf())
+}
+
+func TestFloat64KeyTree(t *testing.T) {
+  tree := NewTree()
+
+  tree.Set(Float64Key(math.NaN()), "first")
+  tree.Set(Float64Key(math.NaN()), "second")
+
+  found, value := tree.Get(Float64Key(math.NaN()))
+  assert.True(t, found)
+  assert.Equal(t, "second", value)
+}
+
+func TestTimeKeyLessThan(t *testing.T) {
+  now := time.Now()
+  x := NewTimeKey(now)
+  y := NewTimeKey(now.Add(time.Second))
+
+  assert.True(t, x.LessThan(y))
+  assert.False(t, y.LessThan(x))
+  assert.False(t, x.LessThan(x))
+}
+
+func TestTimeKeyEqualTo(t *testing.T) {
+  now := time.Now()
+
+  assert.True(t, NewTimeKey(now).EqualTo(NewTimeKey(now)))
+  assert.True(t, NewTimeKey(now).EqualTo(NewTimeKey(now.UTC())))
+  assert.False(t, NewTimeKey(now).EqualTo(NewTimeKey(now.Add(1))))
+}
+
+func TestTimeKeyGreaterThan(t *testing.T) {
+  now := time.Now()
+  x := NewTimeKey(now)
+  y := NewTimeKey(now.Add(time.Second))
+
+  assert.True(t, y.GreaterThan(x))
+  assert.False(t, x.GreaterThan(y))
+  assert.False(t, x.GreaterThan(x))
+}
+
+func TestTimeKeyMonotonic(t *testing.T) {
+  now := time.Now()
+  // Same wall clock, different monotonic reading
+  earlier := TimeKey{ now }
+  later := TimeKey{ now.Add(time.Hour).Add(-time.Hour) }
+  wall := TimeKey{ now.Round(0) }
+
+  assert.True(t, earlier.EqualTo(later))
+  assert.True(t, earlier.EqualTo(wall))
+  assert.False(t, earlier.LessThan(wall))
+  assert.False(t, wall.GreaterThan(earlier))
+
+  assert.Equal(t, now.Round(0), NewTimeKey(now).Time)
+}
+
+func TestTimeKeyValueOf(t *testing.T) {
+  now := time.Unix(100, 0)
+  assert.Equal(t, now, NewTimeKey(now).ValueOf())
+}
+
+func TestUUIDKeyLessThan(t *testing.T) {
+  a := UUIDKey{ 0x00, 0x01 }
+  b := UUIDKey{ 0x00, 0x02 }
+  c := UUIDKey{ 0xFF }
+
+  assert.True(t, a.LessThan(b))
+  assert.True(t, b.LessThan(c))
+  assert.False(t, c.LessThan(a))
+  assert.False(t, a.LessThan(a))
+}
+
+func TestUUIDKeyEqualTo(t *testing.T) {
+  a := UUIDKey{ 0x00, 0x01 }
+  b := UUIDKey{ 0x00, 0x01 }
+  c := UUIDKey{ 0x00, 0x02 }
+
+  assert.True(t, a.EqualTo(b))
+  assert.False(t, a.EqualTo(c))
+}
+
+func TestUUIDKeyGreaterThan(t *testing.T) {
+  a := UUIDKey{ 0x00, 0x01 }
+  c := UUIDKey{ 0xFF }
+
+  assert.True(t, c.GreaterThan(a))
+  assert.False(t, a.GreaterThan(c))
+  assert.False(t, a.GreaterThan(a))
+}
+
+func TestUUIDKeyValueOf(t *testing.T) {
+  assert.Equal(t, [16]byte{ 0x01 }, UUIDKey{ 0x01 }.ValueOf())
+}
+
+func TestParseUUIDKey(t *testing.T) {
+  key, err := ParseUUIDKey("123e4567-e89b-12d3-a456-426614174000")
+  assert.NoError(t, err)
+  assert.Equal(t, UUIDKey{ 0x12, 0x3e, 0x45, 0x67, 0xe8, 0x9b, 0x12, 0xd3, 0xa4, 0x56, 0x42, 0x66, 0x14, 0x17, 0x40, 0x00 }, key)
+  assert.Equal(t, "123e4567-e89b-12d3-a456-426614174000", key.String())
+
+  for _, s := range []string{ "", "123e4567e89b12d3a456426614174000", "123e4567-e89b-12d3-a456-42661417400g", "123e4567-e89b-12d3-a456_426614174000" } {
+    _, err = ParseUUIDKey(s)
+    assert.Equal(t, ErrInvalidUUID, err, s)
+  }
+}
+
+func TestMismatchedKeyTypes(t *testing.T) {
+  keys := []Comparable{
+    IntKey(1),
+    StringKey("a"),
+    ByteSliceKey{ 0x01 },
+    LexicalByteSliceKey{ 0x01 },
+    Int64Key(1),
+    Uint64Key(1),
+    Float64Key(1),
+    NewTimeKey(time.Unix(1, 0)),
+    UUIDKey{ 0x01 },
+    MustEncodeTuple(1),
+  }
+
+  for i, a := range keys {
+    for j, b := range keys {
+      if i == j { continue }
+      assert.NotPanics(t, func() {
+        assert.False(t, a.LessThan(b), "%T < %T", a, b)
+        assert.False(t, a.EqualTo(b), "%T == %T", a, b)
+        assert.False(t, a.GreaterThan(b), "%T > %T", a, b)
+        if prefixable, ok := a.(Prefixable); ok {
+          assert.False(t, prefixable.HasPrefix(b), "%T has prefix %T", a, b)
+        }
+      })
+      assert.Equal(t, 1, DefaultComparator(a, b))
+    }
+  }
+}
@@ -0,0 +1,57 @@
+package binarytree
+
+import(
+  "context"
+)
+
+// ErrorIterator is a func that can iterate a tree and stop the walk by returning an error
+type ErrorIterator func(key Comparable, value interface{}) error
+
+// The number of nodes visited between checks of a walk's context
+const walkContextInterval = 256
+
+// Iterate the tree with the function in the supplied direction until the context is done or iterator returns an error.
+// Return ctx.Err() if the context was done, the error iterator returned, or nil if every node was visited.
+// The context is checked before the walk begins and then every few hundred nodes.
+func (me *Tree) WalkContext(ctx context.Context, iterator ErrorIterator, forward bool) error {
+  if err := ctx.Err(); err != nil { return err }
+  if me.root == nil { return nil }
+  check := contextChecker(ctx, iterator, me.expired())
+  var err error
+  me.root.walk(func(node *Node) bool {
+    err = check(node)
+    return err == nil
+  }, forward)
+  return err
+}
+
+// Iterate the tree for all Nodes between the two keys, inclusive, as WalkContext does.
+func (me *Tree) WalkRangeContext(ctx context.Context, iterator ErrorIterator, from Comparable, to Comparable, forward bool) error {
+  if err := ctx.Err(); err != nil { return err }
+  if me.root == nil { return nil }
+  check := contextChecker(ctx, iterator, me.expired())
+  var err error
+  me.root.walkRange(func(node *Node) bool {
+    err = check(node)
+    return err == nil
+  }, from, to, me.Comparator(), forward)
+  return err
+}
+
+// Return a func that calls iterator for a node, first returning ctx.Err() if the context is done
+// every walkContextInterval calls. Nodes whose keys have expired are skipped if expired is not nil.
+func contextChecker(ctx context.Context, iterator ErrorIterator, expired func(key Comparable) bool) func(node *Node) error {
+  done := ctx.Done()
+  count := 0
+  return func(node *Node) error {
+    count++
+    if done != nil && count % walkContextInterval == 0 {
+      select {
+        case <-done: return ctx.Err()
+        default:
+      }
+    }
+    if expired != nil && expired(node.Key) { return nil }
+    return iterator(node.Key, node.Value)
+  }
+}
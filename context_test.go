@@ -0,0 +1,81 @@
+package binarytree
+
+import (
+  "context"
+  "errors"
+  "testing"
+  "github.com/stretchr/testify/assert"
+)
+
+func TestWalkContext(t *testing.T) {
+  tree := getTestTreeWithKeys(getTestKeys(10, true))
+  for _, forward := range []bool{ true, false } {
+    expected, actual := []Comparable{}, []Comparable{}
+    tree.Walk(func(key Comparable, value interface{}) { expected = append(expected, key) }, forward)
+    err := tree.WalkContext(context.Background(), func(key Comparable, value interface{}) error {
+      actual = append(actual, key)
+      return nil
+    }, forward)
+    assert.Nil(t, err)
+    assert.Equal(t, expected, actual)
+  }
+  assert.Nil(t, NewTree().WalkContext(context.Background(), func(key Comparable, value interface{}) error { return errors.New("called") }, true))
+}
+
+func TestWalkContextIteratorError(t *testing.T) {
+  tree := getTestTreeWithKeys(getTestKeys(10, true))
+  stop := errors.New("stop")
+  keys := []Comparable{}
+  err := tree.WalkContext(context.Background(), func(key Comparable, value interface{}) error {
+    keys = append(keys, key)
+    if key == IntKey(3) { return stop }
+    return nil
+  }, true)
+  assert.Equal(t, stop, err)
+  assert.Equal(t, []Comparable{ IntKey(0), IntKey(1), IntKey(2), IntKey(3) }, keys)
+}
+
+func TestWalkContextCancelled(t *testing.T) {
+  tree := getTestTreeWithKeys(getTestKeys(10000, true))
+  ctx, cancel := context.WithCancel(context.Background())
+  cancel()
+  calls := 0
+  err := tree.WalkContext(ctx, func(key Comparable, value interface{}) error { calls++; return nil }, true)
+  assert.Equal(t, context.Canceled, err)
+  assert.Equal(t, 0, calls)
+
+  // Cancelling during the walk stops it at the next check
+  ctx, cancel = context.WithCancel(context.Background())
+  calls = 0
+  err = tree.WalkRangeContext(ctx, func(key Comparable, value interface{}) error {
+    calls++
+    if calls == 1000 { cancel() }
+    return nil
+  }, IntKey(0), IntKey(9999), false)
+  assert.Equal(t, context.Canceled, err)
+  assert.True(t, calls >= 1000 && calls < 1000 + walkContextInterval, calls)
+}
+
+func TestWalkRangeContext(t *testing.T) {
+  tree := getTestTreeWithKeys(getTestKeys(20, true))
+  for _, forward := range []bool{ true, false } {
+    expected, actual := []Comparable{}, []Comparable{}
+    tree.WalkRange(func(key Comparable, value interface{}) { expected = append(expected, key) }, IntKey(5), IntKey(12), forward)
+    err := tree.WalkRangeContext(context.Background(), func(key Comparable, value interface{}) error {
+      actual = append(actual, key)
+      return nil
+    }, IntKey(5), IntKey(12), forward)
+    assert.Nil(t, err)
+    assert.Equal(t, expected, actual)
+  }
+
+  stop := errors.New("stop")
+  keys := []Comparable{}
+  err := tree.WalkRangeContext(context.Background(), func(key Comparable, value interface{}) error {
+    keys = append(keys, key)
+    if len(keys) == 2 { return stop }
+    return nil
+  }, IntKey(5), IntKey(12), false)
+  assert.Equal(t, stop, err)
+  assert.Equal(t, []Comparable{ IntKey(12), IntKey(11) }, keys)
+}
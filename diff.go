@@ -0,0 +1,135 @@
+package binarytree
+
+import(
+  "reflect"
+)
+
+// ChangeOp identifies the kind of a Change
+type ChangeOp int
+
+const (
+  // A key is in the newer tree only.
+  ChangeAdd ChangeOp = iota
+  // A key is in the older tree only.
+  ChangeRemove
+  // A key is in both trees with values that are not equal.
+  ChangeUpdate
+)
+
+// Return the name of the change op
+func (me ChangeOp) String() string {
+  switch me {
+    case ChangeAdd: return "add"
+    case ChangeRemove: return "remove"
+    case ChangeUpdate: return "update"
+  }
+  return "unknown"
+}
+
+// Change is a difference in a single key between two trees, as found by Diff. OldValue is nil for
+// ChangeAdd and NewValue is nil for ChangeRemove.
+type Change struct {
+  Op ChangeOp
+  Key Comparable
+  OldValue interface{}
+  NewValue interface{}
+}
+
+// EqualFunc is a func that reports whether two values are equal, as called by Diff
+type EqualFunc func(a interface{}, b interface{}) bool
+
+// ChangeIterator is a func that receives changes in key order, and can stop DiffWalk by returning an error
+type ChangeIterator func(change Change) error
+
+// Return the changes that turn tree a into tree b, in key order. equal compares the values of keys in
+// both trees, and may be nil to use reflect.DeepEqual. Both trees must order keys the same way.
+func Diff(a *Tree, b *Tree, equal EqualFunc) []Change {
+  changes := []Change{}
+  DiffWalk(a, b, equal, func(change Change) error {
+    changes = append(changes, change)
+    return nil
+  })
+  return changes
+}
+
+// Call iterator with each change that turns tree a into tree b, in key order, as Diff does, until
+// iterator returns an error. Return the error iterator returned, or nil.
+//
+// Both trees are walked together in a single pass, using memory proportional to their heights.
+func DiffWalk(a *Tree, b *Tree, equal EqualFunc, iterator ChangeIterator) error {
+  if equal == nil { equal = reflect.DeepEqual }
+  cmp := a.Comparator()
+  older, newer := newNodeCursor(a.root, a.expired()), newNodeCursor(b.root, b.expired())
+  nodeA, nodeB := older.next(), newer.next()
+  for nodeA != nil || nodeB != nil {
+    var change *Change
+    c := 0
+    switch {
+      case nodeB == nil: c = -1
+      case nodeA == nil: c = 1
+      default: c = cmp(nodeA.Key, nodeB.Key)
+    }
+    switch {
+      case c < 0:
+        change = &Change{ Op: ChangeRemove, Key: nodeA.Key, OldValue: nodeA.Value }
+        nodeA = older.next()
+      case c > 0:
+        change = &Change{ Op: ChangeAdd, Key: nodeB.Key, NewValue: nodeB.Value }
+        nodeB = newer.next()
+      default:
+        if !equal(nodeA.Value, nodeB.Value) {
+          change = &Change{ Op: ChangeUpdate, Key: nodeB.Key, OldValue: nodeA.Value, NewValue: nodeB.Value }
+        }
+        nodeA, nodeB = older.next(), newer.next()
+    }
+    if change == nil { continue }
+    if err := iterator(*change); err != nil { return err }
+  }
+  return nil
+}
+
+// Apply the changes to the tree, setting the new value of every added or updated key and clearing
+// every removed key. Applying the changes from Diff(a, b, equal) to a copy of a reproduces b. The
+// changes are applied as a single Batch, so either all of them are applied or, if an error is
+// returned, none are.
+func Patch(tree *Tree, changes []Change) error {
+  batch := NewBatch()
+  for _, change := range changes {
+    if change.Op == ChangeRemove {
+      batch.Clear(change.Key)
+    } else {
+      batch.Set(change.Key, change.NewValue)
+    }
+  }
+  return tree.Apply(batch)
+}
+
+// nodeCursor iterates a subtree in order, holding only the path to the next node
+type nodeCursor struct {
+  stack []*Node
+  expired func(key Comparable) bool
+}
+
+// Return a cursor positioned before the first node of the subtree, which may be nil. Nodes whose keys
+// have expired are skipped if expired is not nil.
+func newNodeCursor(root *Node, expired func(key Comparable) bool) *nodeCursor {
+  cursor := &nodeCursor{ expired: expired }
+  cursor.pushLeft(root)
+  return cursor
+}
+
+// Push node and its chain of left children.
+func (me *nodeCursor) pushLeft(node *Node) {
+  for ; node != nil; node = node.Left { me.stack = append(me.stack, node) }
+}
+
+// Return the next node in order, or nil if there are no more.
+func (me *nodeCursor) next() *Node {
+  for len(me.stack) > 0 {
+    node := me.stack[len(me.stack)-1]
+    me.stack = me.stack[:len(me.stack)-1]
+    me.pushLeft(node.Right)
+    if me.expired == nil || !me.expired(node.Key) { return node }
+  }
+  return nil
+}
@@ -0,0 +1,93 @@
+package binarytree
+
+import (
+  "errors"
+  "math/rand"
+  "testing"
+  "github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+  a, b := NewTree(), NewTree()
+  for i:=0; i<10; i++ { a.Set(IntKey(i), i) }
+  for i:=9; i>=2; i-- { b.Set(IntKey(i), i) }
+  b.Set(IntKey(5), "five")
+  b.Set(IntKey(12), 12)
+
+  assert.Equal(t, []Change{
+    { Op: ChangeRemove, Key: IntKey(0), OldValue: 0 },
+    { Op: ChangeRemove, Key: IntKey(1), OldValue: 1 },
+    { Op: ChangeUpdate, Key: IntKey(5), OldValue: 5, NewValue: "five" },
+    { Op: ChangeAdd, Key: IntKey(12), NewValue: 12 },
+  }, Diff(a, b, nil))
+
+  assert.Equal(t, []Change{}, Diff(a, a.Copy(), nil))
+  assert.Equal(t, []Change{}, Diff(NewTree(), NewTree(), nil))
+  assert.Equal(t, 10, len(Diff(a, NewTree(), nil)))
+  assert.Equal(t, 10, len(Diff(NewTree(), a, nil)))
+}
+
+func TestDiffEqualFunc(t *testing.T) {
+  a, b := NewTree(), NewTree()
+  a.Set(IntKey(1), 1)
+  a.Set(IntKey(2), 2)
+  b.Set(IntKey(1), 3)
+  b.Set(IntKey(2), 4)
+  // Only odd and even values are told apart
+  parity := func(x, y interface{}) bool { return x.(int) % 2 == y.(int) % 2 }
+  assert.Equal(t, []Change{}, Diff(a, b, parity))
+  b.Set(IntKey(2), 5)
+  assert.Equal(t, []Change{ { Op: ChangeUpdate, Key: IntKey(2), OldValue: 2, NewValue: 5 } }, Diff(a, b, parity))
+}
+
+func TestDiffWalk(t *testing.T) {
+  a, b := NewTree(), NewTree()
+  for i:=0; i<10; i++ { b.Set(IntKey(i), i) }
+  stop := errors.New("stop")
+  keys := []Comparable{}
+  err := DiffWalk(a, b, nil, func(change Change) error {
+    keys = append(keys, change.Key)
+    if len(keys) == 3 { return stop }
+    return nil
+  })
+  assert.Equal(t, stop, err)
+  assert.Equal(t, []Comparable{ IntKey(0), IntKey(1), IntKey(2) }, keys)
+}
+
+func TestPatch(t *testing.T) {
+  r := rand.New(rand.NewSource(1))
+  a := getTestTreeWithKeys(getTestKeys(500, true))
+  b := a.Copy()
+  for i:=0; i<300; i++ {
+    key := IntKey(r.Intn(700))
+    if r.Intn(2) == 0 {
+      b.Clear(key)
+    } else {
+      b.Set(key, r.Intn(5))
+    }
+  }
+  changes := Diff(a, b, nil)
+  assert.True(t, len(changes) > 0)
+  patched := a.Copy()
+  assert.Nil(t, Patch(patched, changes))
+  assert.Equal(t, []Change{}, Diff(patched, b, nil))
+
+  // A change set that cannot be applied leaves the tree unchanged
+  assert.ErrorIs(t, Patch(patched, []Change{ { Op: ChangeAdd, Key: IntKey(1000), NewValue: 1 }, { Op: ChangeAdd } }), ErrNilKey)
+  assert.Equal(t, []Change{}, Diff(patched, b, nil))
+}
+
+func TestChangeOpString(t *testing.T) {
+  assert.Equal(t, "add", ChangeAdd.String())
+  assert.Equal(t, "remove", ChangeRemove.String())
+  assert.Equal(t, "update", ChangeUpdate.String())
+  assert.Equal(t, "unknown", ChangeOp(99).String())
+}
+
+func BenchmarkDiff(b *testing.B) {
+  treeA := getTestTreeWithKeys(benchmarkKeys(100000, "Random"))
+  treeB := treeA.Copy()
+  for i:=0; i<100; i++ { treeB.Set(IntKey(i * 1000), -1) }
+  b.ResetTimer()
+  for i:=0; i<b.N; i++ { Diff(treeA, treeB, nil) }
+}
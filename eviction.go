@@ -0,0 +1,165 @@
+package binarytree
+
+import(
+  "container/list"
+)
+
+// EvictionPolicy chooses which entry a BoundedTree evicts when it is over its limits.
+// The BoundedTree tells the policy about each key as it is added, accessed and removed,
+// and asks it for a victim. The built in policies are returned by NewLRUPolicy,
+// NewLFUPolicy, NewLowestKeyPolicy and NewHighestKeyPolicy.
+type EvictionPolicy interface {
+  // The key was added to the tree.
+  Added(key Comparable)
+  // The key was read with Get, or its value replaced with Set.
+  Accessed(key Comparable)
+  // The key was removed from the tree, by Clear or by eviction.
+  Removed(key Comparable)
+  // Return the key to evict next from the supplied tree, or (false, nil) if there is none.
+  Victim(tree *Tree) (bool, Comparable)
+}
+
+// Policies that index keys are told the BoundedTree's Comparator before use.
+type comparatorSetter interface {
+  setComparator(cmp Comparator)
+}
+
+// lruPolicy evicts the least recently used key
+type lruPolicy struct {
+  order *list.List
+  elements *Tree
+}
+
+// Return a policy that evicts the least recently added or accessed key.
+func NewLRUPolicy() EvictionPolicy {
+  return &lruPolicy{ order: list.New(), elements: NewTree() }
+}
+
+func (me *lruPolicy) setComparator(cmp Comparator) {
+  me.elements = NewTreeWithComparator(cmp)
+}
+
+func (me *lruPolicy) Added(key Comparable) {
+  me.elements.Set(key, me.order.PushFront(key))
+}
+
+func (me *lruPolicy) Accessed(key Comparable) {
+  found, element := me.elements.Get(key)
+  if !found { return }
+  me.order.MoveToFront(element.(*list.Element))
+}
+
+func (me *lruPolicy) Removed(key Comparable) {
+  found, element := me.elements.Get(key)
+  if !found { return }
+  me.order.Remove(element.(*list.Element))
+  me.elements.Clear(key)
+}
+
+func (me *lruPolicy) Victim(tree *Tree) (bool, Comparable) {
+  back := me.order.Back()
+  if back == nil { return false, nil }
+  return true, back.Value.(Comparable)
+}
+
+// lfuPolicy evicts the least frequently used key, keeping a list of keys for each use count
+type lfuPolicy struct {
+  entries *Tree
+  buckets map[uint64]*list.List
+  min uint64
+}
+
+type lfuEntry struct {
+  count uint64
+  element *list.Element
+}
+
+// Return a policy that evicts the least frequently accessed key, and of those the least recently used.
+func NewLFUPolicy() EvictionPolicy {
+  return &lfuPolicy{ entries: NewTree(), buckets: map[uint64]*list.List{} }
+}
+
+func (me *lfuPolicy) setComparator(cmp Comparator) {
+  me.entries = NewTreeWithComparator(cmp)
+}
+
+// Add the key to the front of the bucket for count.
+func (me *lfuPolicy) push(key Comparable, count uint64) *list.Element {
+  bucket, ok := me.buckets[count]
+  if !ok {
+    bucket = list.New()
+    me.buckets[count] = bucket
+  }
+  return bucket.PushFront(key)
+}
+
+// Remove the element from the bucket for count, dropping the bucket if it is empty.
+func (me *lfuPolicy) pull(element *list.Element, count uint64) {
+  bucket := me.buckets[count]
+  bucket.Remove(element)
+  if bucket.Len() == 0 { delete(me.buckets, count) }
+}
+
+func (me *lfuPolicy) Added(key Comparable) {
+  me.entries.Set(key, &lfuEntry{ count: 1, element: me.push(key, 1) })
+  me.min = 1
+}
+
+func (me *lfuPolicy) Accessed(key Comparable) {
+  found, value := me.entries.Get(key)
+  if !found { return }
+  entry := value.(*lfuEntry)
+  me.pull(entry.element, entry.count)
+  if _, ok := me.buckets[me.min]; !ok && me.min == entry.count { me.min++ }
+  entry.count++
+  entry.element = me.push(key, entry.count)
+}
+
+func (me *lfuPolicy) Removed(key Comparable) {
+  found, value := me.entries.Get(key)
+  if !found { return }
+  entry := value.(*lfuEntry)
+  me.pull(entry.element, entry.count)
+  me.entries.Clear(key)
+}
+
+func (me *lfuPolicy) Victim(tree *Tree) (bool, Comparable) {
+  if len(me.buckets) == 0 { return false, nil }
+  // The minimum is only tracked exactly on insert and access, so find it again after removals
+  if _, ok := me.buckets[me.min]; !ok {
+    first := true
+    for count := range me.buckets {
+      if first || count < me.min { me.min, first = count, false }
+    }
+  }
+  return true, me.buckets[me.min].Back().Value.(Comparable)
+}
+
+// keyOrderPolicy evicts the lowest or highest key in the tree
+type keyOrderPolicy struct {
+  highest bool
+}
+
+// Return a policy that evicts the lowest key in the tree.
+func NewLowestKeyPolicy() EvictionPolicy {
+  return &keyOrderPolicy{ highest: false }
+}
+
+// Return a policy that evicts the highest key in the tree.
+func NewHighestKeyPolicy() EvictionPolicy {
+  return &keyOrderPolicy{ highest: true }
+}
+
+func (me *keyOrderPolicy) Added(key Comparable) {}
+func (me *keyOrderPolicy) Accessed(key Comparable) {}
+func (me *keyOrderPolicy) Removed(key Comparable) {}
+
+func (me *keyOrderPolicy) Victim(tree *Tree) (bool, Comparable) {
+  var key Comparable
+  if me.highest {
+    key, _ = tree.Last()
+  } else {
+    key, _ = tree.First()
+  }
+  return key != nil, key
+}
@@ -0,0 +1,68 @@
+package binarytree
+
+import (
+  "testing"
+  "github.com/stretchr/testify/assert"
+)
+
+func TestLRUPolicy(t *testing.T) {
+  policy := NewLRUPolicy()
+  found, _ := policy.Victim(nil)
+  assert.False(t, found)
+
+  for i:=1; i<=3; i++ { policy.Added(IntKey(i)) }
+  _, victim := policy.Victim(nil)
+  assert.Equal(t, IntKey(1), victim)
+
+  policy.Accessed(IntKey(1))
+  _, victim = policy.Victim(nil)
+  assert.Equal(t, IntKey(2), victim)
+
+  policy.Removed(IntKey(2))
+  policy.Removed(IntKey(9))
+  _, victim = policy.Victim(nil)
+  assert.Equal(t, IntKey(3), victim)
+}
+
+func TestLFUPolicy(t *testing.T) {
+  policy := NewLFUPolicy()
+  found, _ := policy.Victim(nil)
+  assert.False(t, found)
+
+  for i:=1; i<=3; i++ { policy.Added(IntKey(i)) }
+  policy.Accessed(IntKey(1))
+  policy.Accessed(IntKey(1))
+  policy.Accessed(IntKey(2))
+
+  // Of the keys used least often, the least recently used goes first
+  _, victim := policy.Victim(nil)
+  assert.Equal(t, IntKey(3), victim)
+  policy.Removed(IntKey(3))
+  _, victim = policy.Victim(nil)
+  assert.Equal(t, IntKey(2), victim)
+
+  // A new key is used least often
+  policy.Added(IntKey(4))
+  _, victim = policy.Victim(nil)
+  assert.Equal(t, IntKey(4), victim)
+  policy.Removed(IntKey(4))
+  policy.Removed(IntKey(2))
+  _, victim = policy.Victim(nil)
+  assert.Equal(t, IntKey(1), victim)
+  policy.Removed(IntKey(1))
+  found, _ = policy.Victim(nil)
+  assert.False(t, found)
+}
+
+func TestKeyOrderPolicies(t *testing.T) {
+  tree := NewTree()
+  lowest, highest := NewLowestKeyPolicy(), NewHighestKeyPolicy()
+  found, _ := lowest.Victim(tree)
+  assert.False(t, found)
+
+  for _, key := range []int{ 5, 2, 8 } { tree.Set(IntKey(key), key) }
+  _, victim := lowest.Victim(tree)
+  assert.Equal(t, IntKey(2), victim)
+  _, victim = highest.Victim(tree)
+  assert.Equal(t, IntKey(8), victim)
+}
@@ -0,0 +1,107 @@
+package binarytree
+
+import(
+  "bufio"
+  "fmt"
+  "io"
+  "strings"
+)
+
+// DOTOptions controls the output of Tree.WriteDOT. The zero value is valid.
+type DOTOptions struct {
+  // The name of the graph. Defaults to "binarytree".
+  Name string
+  // Format a key for a node label. Defaults to fmt's %v.
+  KeyFormatter func(key Comparable) string
+  // Format a value for a node label. If nil, values are not shown.
+  ValueFormatter func(value interface{}) string
+  // If not nil, highlight the search path FindNearest takes for this key.
+  Highlight Comparable
+}
+
+// Write the structure of the tree to w as a Graphviz DOT digraph. Missing children are drawn as
+// points so that left and right children can be told apart. opts may be nil.
+func (me *Tree) WriteDOT(w io.Writer, opts *DOTOptions) error {
+  if opts == nil { opts = &DOTOptions{} }
+  name := opts.Name
+  if name == "" { name = "binarytree" }
+  keyFormatter := opts.KeyFormatter
+  if keyFormatter == nil { keyFormatter = func(key Comparable) string { return fmt.Sprintf("%v", key) } }
+
+  highlighted := map[*Node]bool{}
+  if opts.Highlight != nil && me.root != nil {
+    node, stack := me.root.findNearest(opts.Highlight, me.Comparator())
+    for _, ancestor := range stack { highlighted[ancestor] = true }
+    highlighted[node] = true
+  }
+
+  out := bufio.NewWriter(w)
+  fmt.Fprintf(out, "digraph %s {\n", dotQuote(name))
+  fmt.Fprintf(out, "  node [shape=box];\n")
+  ids := map[*Node]int{}
+  nils := 0
+  var write func(node *Node)
+  write = func(node *Node) {
+    id := len(ids)
+    ids[node] = id
+    label := keyFormatter(node.Key)
+    if opts.ValueFormatter != nil { label += "\n" + opts.ValueFormatter(node.Value) }
+    style := ""
+    if highlighted[node] { style = ", style=filled, fillcolor=yellow" }
+    fmt.Fprintf(out, "  n%d [label=%s%s];\n", id, dotQuote(label), style)
+    for _, child := range []*Node{ node.Left, node.Right } {
+      if child == nil {
+        fmt.Fprintf(out, "  nil%d [shape=point];\n", nils)
+        fmt.Fprintf(out, "  n%d -> nil%d;\n", id, nils)
+        nils++
+        continue
+      }
+      childID := len(ids)
+      write(child)
+      edgeStyle := ""
+      if highlighted[node] && highlighted[child] { edgeStyle = " [color=red, penwidth=2]" }
+      fmt.Fprintf(out, "  n%d -> n%d%s;\n", id, childID, edgeStyle)
+    }
+  }
+  if me.root != nil { write(me.root) }
+  fmt.Fprintf(out, "}\n")
+  return out.Flush()
+}
+
+func dotQuote(s string) string {
+  s = strings.ReplaceAll(s, "\\", "\\\\")
+  s = strings.ReplaceAll(s, "\"", "\\\"")
+  s = strings.ReplaceAll(s, "\n", "\\n")
+  return "\"" + s + "\""
+}
+
+// Return the tree drawn sideways in ASCII, with the root on the left and larger keys above
+// smaller ones, for example:
+//
+//       /-- 7
+//   /-- 6
+//   |   \-- 5
+//   4
+//   |   /-- 3
+//   \-- 2
+//       \-- 1
+func (me *Tree) String() string {
+  if me.root == nil { return "(empty)\n" }
+  b := &strings.Builder{}
+  me.root.writeASCII(b, "", 0)
+  return b.String()
+}
+
+// Write this node's subtree sideways to b. side is 0 for the root, 1 for a right child and -1 for a left child.
+func (me *Node) writeASCII(b *strings.Builder, prefix string, side int) {
+  rightPrefix, leftPrefix, connector := prefix, prefix, ""
+  switch side {
+    case 1:
+      rightPrefix, leftPrefix, connector = prefix + "    ", prefix + "|   ", "/-- "
+    case -1:
+      rightPrefix, leftPrefix, connector = prefix + "|   ", prefix + "    ", "\\-- "
+  }
+  if me.Right != nil { me.Right.writeASCII(b, rightPrefix, 1) }
+  fmt.Fprintf(b, "%s%s%v\n", prefix, connector, me.Key)
+  if me.Left != nil { me.Left.writeASCII(b, leftPrefix, -1) }
+}
@@ -0,0 +1,112 @@
+package binarytree
+
+import (
+  "bytes"
+  "errors"
+  "fmt"
+  "testing"
+  "github.com/stretchr/testify/assert"
+)
+
+func TestWriteDOT(t *testing.T) {
+  tree := NewTree()
+  tree.Set(IntKey(2), "two")
+  tree.Set(IntKey(1), "one")
+  tree.Set(IntKey(3), "three")
+
+  out := &bytes.Buffer{}
+  assert.NoError(t, tree.WriteDOT(out, nil))
+  assert.Equal(t, `digraph "binarytree" {
+  node [shape=box];
+  n0 [label="2"];
+  n1 [label="1"];
+  nil0 [shape=point];
+  n1 -> nil0;
+  nil1 [shape=point];
+  n1 -> nil1;
+  n0 -> n1;
+  n2 [label="3"];
+  nil2 [shape=point];
+  n2 -> nil2;
+  nil3 [shape=point];
+  n2 -> nil3;
+  n0 -> n2;
+}
+`, out.String())
+}
+
+func TestWriteDOTOptions(t *testing.T) {
+  tree := NewTree()
+  tree.Set(IntKey(2), "two")
+  tree.Set(IntKey(1), "one")
+  tree.Set(IntKey(3), "th\"ree")
+
+  out := &bytes.Buffer{}
+  assert.NoError(t, tree.WriteDOT(out, &DOTOptions{
+    Name: "test",
+    KeyFormatter: func(key Comparable) string { return fmt.Sprintf("#%d", key.ValueOf().(int)) },
+    ValueFormatter: func(value interface{}) string { return value.(string) },
+    Highlight: IntKey(3),
+  }))
+  assert.Equal(t, `digraph "test" {
+  node [shape=box];
+  n0 [label="#2\ntwo", style=filled, fillcolor=yellow];
+  n1 [label="#1\none"];
+  nil0 [shape=point];
+  n1 -> nil0;
+  nil1 [shape=point];
+  n1 -> nil1;
+  n0 -> n1;
+  n2 [label="#3\nth\"ree", style=filled, fillcolor=yellow];
+  nil2 [shape=point];
+  n2 -> nil2;
+  nil3 [shape=point];
+  n2 -> nil3;
+  n0 -> n2 [color=red, penwidth=2];
+}
+`, out.String())
+}
+
+func TestWriteDOTEmpty(t *testing.T) {
+  out := &bytes.Buffer{}
+  assert.NoError(t, NewTree().WriteDOT(out, nil))
+  assert.Equal(t, "digraph \"binarytree\" {\n  node [shape=box];\n}\n", out.String())
+}
+
+type failingWriter struct{}
+
+func (me failingWriter) Write(p []byte) (int, error) {
+  return 0, errors.New("write failed")
+}
+
+func TestWriteDOTError(t *testing.T) {
+  tree := NewTree()
+  tree.root = getTestTreeBalanced(1)
+
+  assert.EqualError(t, tree.WriteDOT(failingWriter{}, nil), "write failed")
+}
+
+func TestTreeString(t *testing.T) {
+  tree := NewTree()
+  assert.Equal(t, "(empty)\n", tree.String())
+
+  tree.root = getTestTreeBalanced(1)
+  assert.Equal(t, `    /-- 7
+/-- 6
+|   \-- 5
+4
+|   /-- 3
+\-- 2
+    \-- 1
+`, tree.String())
+
+  tree.root = getTestTreeRightUnbalanced(1)
+  tree.Clear(IntKey(7))
+  assert.Equal(t, `                /-- 6
+            /-- 5
+        /-- 4
+    /-- 3
+/-- 2
+1
+`, tree.String())
+}
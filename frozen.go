@@ -0,0 +1,194 @@
+package binarytree
+
+import(
+  "math/bits"
+)
+
+// FrozenTree is an immutable snapshot of a Tree, as returned by Tree.Freeze, for lookup tables
+// that are built once and read many times.
+//
+// Keys and values are stored in arrays in Eytzinger order: the root is at index 1 and the children
+// of the node at index i are at 2i and 2i+1. There are no child pointers to chase, the top levels
+// of the tree share cache lines, and the nodes a search visits next are at predictable addresses.
+// Searches always run to the bottom of the tree and find the child to visit next by arithmetic on
+// the index. Keys are compared through the tree's Comparator, an indirect call, so the cost of a
+// search is still dominated by comparisons. Go has no portable prefetch instruction, so no explicit
+// prefetching is done.
+type FrozenTree struct {
+  // Index 0 is unused
+  keys []Comparable
+  values []interface{}
+  compare Comparator
+}
+
+// Return an immutable copy of the tree in Eytzinger order. Later changes to the tree do not affect it.
+func (me *Tree) Freeze() *FrozenTree {
+  keys, values := []Comparable{}, []interface{}{}
+  if me.root != nil {
+    visit := me.unexpired(func(node *Node) {
+      keys = append(keys, node.Key)
+      values = append(values, node.Value)
+    })
+    me.root.walk(func(node *Node) bool { visit(node); return true }, true)
+  }
+  frozen := &FrozenTree{ keys: make([]Comparable, len(keys)+1), values: make([]interface{}, len(keys)+1), compare: me.Comparator() }
+  next := 0
+  var place func(i int)
+  place = func(i int) {
+    if i > len(keys) { return }
+    place(2*i)
+    frozen.keys[i], frozen.values[i] = keys[next], values[next]
+    next++
+    place(2*i+1)
+  }
+  place(1)
+  return frozen
+}
+
+// Return the number of keys in the tree.
+func (me *FrozenTree) Len() int {
+  return len(me.keys) - 1
+}
+
+// Search to the bottom of the tree, going right where the key at i compares below limit (0 for less
+// than the key, 1 for less than or equal) and left otherwise. Return the index one past the bottom,
+// whose bits record the path taken.
+func (me *FrozenTree) descend(key Comparable, limit int) int {
+  n := len(me.keys) - 1
+  i := 1
+  for i <= n {
+    right := 0
+    if me.compare(me.keys[i], key) < limit { right = 1 }
+    i = 2*i + right
+  }
+  return i
+}
+
+// Return the index of the first key greater than (or equal to, if orEqual) the supplied key, or 0 if there is none.
+func (me *FrozenTree) above(key Comparable, orEqual bool) int {
+  // Go right past keys less than (or equal to, if not orEqual) the key, left otherwise
+  limit := 0
+  if !orEqual { limit = 1 }
+  i := me.descend(key, limit)
+  // The answer is where the search last went left: drop the trailing right moves (1 bits) and that left move
+  return i >> (bits.TrailingZeros(^uint(i)) + 1)
+}
+
+// Return the index of the last key less than (or equal to, if orEqual) the supplied key, or 0 if there is none.
+func (me *FrozenTree) below(key Comparable, orEqual bool) int {
+  limit := 0
+  if orEqual { limit = 1 }
+  i := me.descend(key, limit)
+  // The answer is where the search last went right: drop the trailing left moves (0 bits) and that right move
+  return i >> (bits.TrailingZeros(uint(i)) + 1)
+}
+
+// Return the key and value at index i, or (false, nil, nil) if i is 0.
+func (me *FrozenTree) at(i int) (bool, Comparable, interface{}) {
+  if i == 0 { return false, nil, nil }
+  return true, me.keys[i], me.values[i]
+}
+
+// Get the value associated with the supplied key. Return (true, value) if found,
+// (false, nil) if not.
+func (me *FrozenTree) Get(key Comparable) (bool, interface{}) {
+  i := me.above(key, true)
+  if i == 0 || me.compare(me.keys[i], key) != 0 { return false, nil }
+  return true, me.values[i]
+}
+
+// Return the largest key less than or equal to the supplied key, and its value.
+// If such a key exists, return (true, key, value), otherwise return (false, nil, nil).
+func (me *FrozenTree) Floor(key Comparable) (bool, Comparable, interface{}) {
+  return me.at(me.below(key, true))
+}
+
+// Return the smallest key greater than or equal to the supplied key, and its value.
+// If such a key exists, return (true, key, value), otherwise return (false, nil, nil).
+func (me *FrozenTree) Ceiling(key Comparable) (bool, Comparable, interface{}) {
+  return me.at(me.above(key, true))
+}
+
+// Return the key and value with the next smallest key than the supplied key.
+// If a smaller key exists, return (true, key, value), otherwise return (false, nil, nil).
+func (me *FrozenTree) Previous(key Comparable) (bool, Comparable, interface{}) {
+  return me.at(me.below(key, false))
+}
+
+// Return the key and value with the next largest key than the supplied key.
+// If a larger key exists, return (true, key, value), otherwise return (false, nil, nil).
+func (me *FrozenTree) Next(key Comparable) (bool, Comparable, interface{}) {
+  return me.at(me.above(key, false))
+}
+
+// Return the first (lowest) key and value in the tree, or nil, nil if the tree is empty.
+func (me *FrozenTree) First() (Comparable, interface{}) {
+  _, key, value := me.at(me.first())
+  return key, value
+}
+
+// Return the last (highest) key and value in the tree, or nil, nil if the tree is empty.
+func (me *FrozenTree) Last() (Comparable, interface{}) {
+  _, key, value := me.at(me.last())
+  return key, value
+}
+
+// Return the index of the lowest key, or 0 if the tree is empty.
+func (me *FrozenTree) first() int {
+  n := len(me.keys) - 1
+  if n == 0 { return 0 }
+  i := 1
+  for 2*i <= n { i = 2*i }
+  return i
+}
+
+// Return the index of the highest key, or 0 if the tree is empty.
+func (me *FrozenTree) last() int {
+  n := len(me.keys) - 1
+  if n == 0 { return 0 }
+  i := 1
+  for 2*i+1 <= n { i = 2*i+1 }
+  return i
+}
+
+// Return the index of the key after the key at index i in order, or 0 if it is the last.
+func (me *FrozenTree) successor(i int) int {
+  n := len(me.keys) - 1
+  if 2*i+1 <= n {
+    i = 2*i+1
+    for 2*i <= n { i = 2*i }
+    return i
+  }
+  // Climb while we are a right child, then once more
+  return i >> (bits.TrailingZeros(^uint(i)) + 1)
+}
+
+// Return the index of the key before the key at index i in order, or 0 if it is the first.
+func (me *FrozenTree) predecessor(i int) int {
+  n := len(me.keys) - 1
+  if 2*i <= n {
+    i = 2*i
+    for 2*i+1 <= n { i = 2*i+1 }
+    return i
+  }
+  // Climb while we are a left child, then once more
+  return i >> (bits.TrailingZeros(uint(i)) + 1)
+}
+
+// Iterate the tree with the function in the supplied direction
+func (me *FrozenTree) Walk(iterator Iterator, forward bool) {
+  if forward {
+    for i := me.first(); i != 0; i = me.successor(i) { iterator(me.keys[i], me.values[i]) }
+  } else {
+    for i := me.last(); i != 0; i = me.predecessor(i) { iterator(me.keys[i], me.values[i]) }
+  }
+}
+
+// Iterate the tree for all keys between the two keys, inclusive
+func (me *FrozenTree) WalkRange(iterator Iterator, from Comparable, to Comparable, forward bool) {
+  if forward {
+    for i := me.above(from, true); i != 0 && me.compare(me.keys[i], to) <= 0; i = me.successor(i) { iterator(me.keys[i], me.values[i]) }
+  } else {
+    for i := me.below(to, true); i != 0 && me.compare(me.keys[i], from) >= 0; i = me.predecessor(i) { iterator(me.keys[i], me.values[i]) }
+  }
+}
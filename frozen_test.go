@@ -0,0 +1,157 @@
+package binarytree
+
+import (
+  "fmt"
+  "testing"
+  "github.com/stretchr/testify/assert"
+)
+
+func TestFreezeLayout(t *testing.T) {
+  tree := NewTree()
+  for i:=1; i<=7; i++ { tree.Set(IntKey(i), i) }
+  frozen := tree.Freeze()
+  assert.Equal(t, []Comparable{ nil, IntKey(4), IntKey(2), IntKey(6), IntKey(1), IntKey(3), IntKey(5), IntKey(7) }, frozen.keys)
+  assert.Equal(t, 7, frozen.Len())
+
+  // The frozen tree does not change with the tree
+  tree.Set(IntKey(8), 8)
+  tree.Clear(IntKey(1))
+  found, _ := frozen.Get(IntKey(1))
+  assert.True(t, found)
+  found, _ = frozen.Get(IntKey(8))
+  assert.False(t, found)
+}
+
+func TestFrozenTreeEmpty(t *testing.T) {
+  frozen := NewTree().Freeze()
+  assert.Equal(t, 0, frozen.Len())
+  found, _ := frozen.Get(IntKey(1))
+  assert.False(t, found)
+  found, _, _ = frozen.Floor(IntKey(1))
+  assert.False(t, found)
+  found, _, _ = frozen.Ceiling(IntKey(1))
+  assert.False(t, found)
+  first, _ := frozen.First()
+  assert.Nil(t, first)
+  last, _ := frozen.Last()
+  assert.Nil(t, last)
+  frozen.Walk(func(key Comparable, value interface{}) { assert.Fail(t, "walked an empty tree") }, true)
+  frozen.WalkRange(func(key Comparable, value interface{}) { assert.Fail(t, "walked an empty tree") }, IntKey(0), IntKey(10), false)
+}
+
+// Every size up to a few complete levels, so that every shape of last level is covered
+func TestFrozenTreeMatchesTree(t *testing.T) {
+  for n:=1; n<=40; n++ {
+    keys := getTestKeys(n, true)
+    for i := range keys { keys[i] *= 2 }
+    tree := getTestTreeWithKeys(keys)
+    frozen := tree.Freeze()
+    for probe:=-1; probe<=2*n; probe++ {
+      key := IntKey(probe)
+      treeFound, treeValue := tree.Get(key)
+      found, value := frozen.Get(key)
+      assert.Equal(t, treeFound, found)
+      assert.Equal(t, treeValue, value)
+
+      treeFound, treeNext, _ := tree.Next(key)
+      found, next, _ := frozen.Next(key)
+      assert.Equal(t, treeFound, found, "Next(%d) of %d", probe, n)
+      assert.Equal(t, treeNext, next, "Next(%d) of %d", probe, n)
+
+      treeFound, treePrevious, _ := tree.Previous(key)
+      found, previous, _ := frozen.Previous(key)
+      assert.Equal(t, treeFound, found, "Previous(%d) of %d", probe, n)
+      assert.Equal(t, treePrevious, previous, "Previous(%d) of %d", probe, n)
+
+      // Keys are even, so odd probes fall between them
+      present := probe % 2 == 0 && probe >= 0 && probe < 2*n
+      found, floor, _ := frozen.Floor(key)
+      if present {
+        assert.Equal(t, key, floor)
+      } else {
+        assert.Equal(t, treeFound, found)
+        assert.Equal(t, treePrevious, floor)
+      }
+      found, ceiling, _ := frozen.Ceiling(key)
+      if present {
+        assert.Equal(t, key, ceiling)
+      } else {
+        assert.Equal(t, treeNext, ceiling, "Ceiling(%d) of %d", probe, n)
+      }
+    }
+
+    for _, forward := range []bool{ true, false } {
+      expected, actual := []Comparable{}, []Comparable{}
+      tree.Walk(func(key Comparable, value interface{}) { expected = append(expected, key) }, forward)
+      frozen.Walk(func(key Comparable, value interface{}) { actual = append(actual, key) }, forward)
+      assert.Equal(t, expected, actual)
+
+      expected, actual = []Comparable{}, []Comparable{}
+      tree.WalkRange(func(key Comparable, value interface{}) { expected = append(expected, key) }, IntKey(3), IntKey(n), forward)
+      frozen.WalkRange(func(key Comparable, value interface{}) { actual = append(actual, key) }, IntKey(3), IntKey(n), forward)
+      assert.Equal(t, expected, actual)
+    }
+
+    first, _ := frozen.First()
+    assert.Equal(t, IntKey(0), first)
+    last, _ := frozen.Last()
+    assert.Equal(t, IntKey(2*(n-1)), last)
+  }
+}
+
+func TestFrozenTreeComparator(t *testing.T) {
+  tree := NewTreeWithComparator(ReverseComparator(DefaultComparator))
+  for i:=1; i<=5; i++ { tree.Set(IntKey(i), i) }
+  frozen := tree.Freeze()
+  first, _ := frozen.First()
+  assert.Equal(t, IntKey(5), first)
+  _, next, _ := frozen.Next(IntKey(3))
+  assert.Equal(t, IntKey(2), next)
+}
+
+func BenchmarkFrozenGet(b *testing.B) {
+  for _, n := range benchmarkSizes {
+    tree := NewTree()
+    keys := benchmarkKeys(n, "Random")
+    for _, key := range keys { tree.Set(IntKey(key), key) }
+    tree.Balance()
+    frozen := tree.Freeze()
+    b.Run(fmt.Sprintf("Tree/%d", n), func(b *testing.B) {
+      for i:=0; i<b.N; i++ { tree.Get(IntKey(keys[i%n])) }
+    })
+    b.Run(fmt.Sprintf("FrozenTree/%d", n), func(b *testing.B) {
+      for i:=0; i<b.N; i++ { frozen.Get(IntKey(keys[i%n])) }
+    })
+  }
+}
+
+func BenchmarkFrozenCeiling(b *testing.B) {
+  for _, n := range benchmarkSizes {
+    tree := NewTree()
+    keys := benchmarkKeys(n, "Random")
+    for _, key := range keys { tree.Set(IntKey(key*2), key) }
+    tree.Balance()
+    frozen := tree.Freeze()
+    // Probe between keys, so that Next on the pointer tree finds the same key as Ceiling
+    b.Run(fmt.Sprintf("Tree/%d", n), func(b *testing.B) {
+      for i:=0; i<b.N; i++ { tree.Next(IntKey(keys[i%n]*2-1)) }
+    })
+    b.Run(fmt.Sprintf("FrozenTree/%d", n), func(b *testing.B) {
+      for i:=0; i<b.N; i++ { frozen.Ceiling(IntKey(keys[i%n]*2-1)) }
+    })
+  }
+}
+
+func BenchmarkFrozenWalkRange(b *testing.B) {
+  n := 100000
+  tree := getTestTreeWithKeys(benchmarkKeys(n, "Random"))
+  tree.Balance()
+  frozen := tree.Freeze()
+  count := 0
+  b.Run("Tree", func(b *testing.B) {
+    for i:=0; i<b.N; i++ { tree.WalkRange(func(key Comparable, value interface{}) { count++ }, IntKey(n/2), IntKey(n/2+200), true) }
+  })
+  b.Run("FrozenTree", func(b *testing.B) {
+    for i:=0; i<b.N; i++ { frozen.WalkRange(func(key Comparable, value interface{}) { count++ }, IntKey(n/2), IntKey(n/2+200), true) }
+  })
+}
@@ -0,0 +1,285 @@
+package binarytree
+
+import(
+  "bytes"
+  "crypto/sha256"
+  "encoding/binary"
+  "errors"
+  "fmt"
+  "hash"
+)
+
+// ErrHashingDisabled is returned by the hash methods of a Tree that EnableHashing has not been called on.
+var ErrHashingDisabled = errors.New("binarytree: hashing is not enabled")
+
+// HashOptions controls how Tree.EnableHashing hashes entries. The zero value is valid.
+type HashOptions struct {
+  // Return a new hash. Defaults to sha256.New.
+  Hash func() hash.Hash
+  // Encode a key for hashing. Defaults to its type and fmt's %#v.
+  KeyEncoder func(key Comparable) []byte
+  // Encode a value for hashing. Defaults to its type and fmt's %#v, which is not suitable for values
+  // holding pointers, as their addresses will be hashed.
+  ValueEncoder func(value interface{}) []byte
+}
+
+// hasher computes the hashes of a Tree's entries
+type hasher struct {
+  opts HashOptions
+  size int
+}
+
+// nodeHash holds the hash of a node's entry, and the XOR of the entry hashes and the number of nodes in its subtree
+type nodeHash struct {
+  entry []byte
+  subtree []byte
+  count int
+}
+
+// Start maintaining hashes of the tree's contents, hashing every entry now and then updating the hashes
+// as the tree changes. opts may be nil.
+//
+// Each entry is hashed from its encoded key and value, and each node holds the XOR of the hashes of the
+// entries in its subtree. The root hash therefore depends only on the tree's contents, not its shape,
+// so trees holding the same entries have the same RootHash however they were built. XOR aggregates
+// detect accidental differences, not deliberate collisions.
+//
+// Hashes are kept up to date by Set, Clear, Apply, Balance and expiry. Changing a Node's Value directly
+// leaves its hashes stale, which Validate reports.
+func (me *Tree) EnableHashing(opts *HashOptions) {
+  if opts == nil { opts = &HashOptions{} }
+  h := &hasher{ opts: *opts }
+  if h.opts.Hash == nil { h.opts.Hash = sha256.New }
+  if h.opts.KeyEncoder == nil { h.opts.KeyEncoder = func(key Comparable) []byte { return fmt.Appendf(nil, "%T:%#v", key, key) } }
+  if h.opts.ValueEncoder == nil { h.opts.ValueEncoder = func(value interface{}) []byte { return fmt.Appendf(nil, "%T:%#v", value, value) } }
+  h.size = h.opts.Hash().Size()
+  me.hasher = h
+  me.rehashAll(true)
+}
+
+// Return the hash of the node's key and value.
+func (me *hasher) entry(node *Node) []byte {
+  key, value := me.opts.KeyEncoder(node.Key), me.opts.ValueEncoder(node.Value)
+  h := me.opts.Hash()
+  h.Write(binary.AppendUvarint(nil, uint64(len(key))))
+  h.Write(key)
+  h.Write(value)
+  return h.Sum(nil)
+}
+
+// XOR src into dst.
+func xorHash(dst []byte, src []byte) {
+  for i := range src { dst[i] ^= src[i] }
+}
+
+// Recompute the node's subtree hash and count from its children, and its entry hash if it has none or entry is true.
+// Children with no hash yet are hashed first.
+func (me *hasher) update(node *Node, entry bool) {
+  if node.hash == nil || entry {
+    node.hash = &nodeHash{ entry: me.entry(node) }
+  }
+  subtree := append([]byte{}, node.hash.entry...)
+  count := 1
+  for _, child := range []*Node{ node.Left, node.Right } {
+    if child == nil { continue }
+    if child.hash == nil { me.update(child, true) }
+    xorHash(subtree, child.hash.subtree)
+    count += child.hash.count
+  }
+  node.hash.subtree, node.hash.count = subtree, count
+}
+
+// Recompute the subtree hashes of every node, and their entry hashes if entries is true or they have none.
+func (me *Tree) rehashAll(entries bool) {
+  if me.hasher == nil || me.root == nil { return }
+  var rehash func(node *Node)
+  rehash = func(node *Node) {
+    if node.Left != nil { rehash(node.Left) }
+    if node.Right != nil { rehash(node.Right) }
+    me.hasher.update(node, entries)
+  }
+  rehash(me.root)
+}
+
+// Copy the hashes of the subtree at from to the identically shaped subtree at to. The hash slices are
+// never changed in place, so they are shared.
+func copyHashes(from *Node, to *Node) {
+  if from.hash != nil {
+    hash := *from.hash
+    to.hash = &hash
+  }
+  if from.Left != nil { copyHashes(from.Left, to.Left) }
+  if from.Right != nil { copyHashes(from.Right, to.Right) }
+}
+
+// Recompute the hashes of the nodes on the search path for key, from the bottom up. If the key is found
+// and entry is true, its entry hash is recomputed too.
+//
+// After a Set or Clear of key, every node whose subtree changed is on this path: its ancestors, and when
+// a node with two children is removed, the right spine of its left subtree that its right subtree was moved onto.
+func (me *Tree) rehashPath(key Comparable, entry bool) {
+  if me.hasher == nil { return }
+  cmp := me.Comparator()
+  path := []*Node{}
+  for node := me.root; node != nil; {
+    path = append(path, node)
+    c := cmp(key, node.Key)
+    if c == 0 { break }
+    if c < 0 {
+      node = node.Left
+    } else {
+      node = node.Right
+    }
+  }
+  for i:=len(path)-1; i>=0; i-- {
+    node := path[i]
+    me.hasher.update(node, entry && i == len(path)-1 && cmp(key, node.Key) == 0)
+  }
+}
+
+// Return the XOR of the hashes of every entry in the tree, or ErrHashingDisabled. Trees with the same
+// entries, hashed with the same HashOptions, have the same root hash.
+func (me *Tree) RootHash() ([]byte, error) {
+  hash, _, err := me.RangeHash(nil, nil)
+  return hash, err
+}
+
+// Return the XOR of the hashes of the entries with keys between the two keys, inclusive, and the number
+// of entries, or ErrHashingDisabled. Either key may be nil for an open range. Only the nodes on the
+// search paths to the two keys are visited.
+func (me *Tree) RangeHash(from Comparable, to Comparable) ([]byte, int, error) {
+  if me.hasher == nil { return nil, 0, ErrHashingDisabled }
+  hash, count := me.rangeHash(keyBound{ key: from, inclusive: true }, keyBound{ key: to, inclusive: true })
+  return hash, count, nil
+}
+
+// Return the XOR of the entry hashes of the nodes between the bounds, and their number. Expired
+// entries are left out.
+func (me *Tree) rangeHash(lower keyBound, upper keyBound) ([]byte, int) {
+  cmp := me.Comparator()
+  sum := make([]byte, me.hasher.size)
+  node := me.topmost(lower, upper)
+  if node == nil { return sum, 0 }
+  xorHash(sum, node.hash.entry)
+  count := 1
+  // Below it, the range is the nodes of the left subtree above lower and the nodes of the right subtree below upper
+  for left := node.Left; left != nil; {
+    if lower.admitsAbove(left.Key, cmp) {
+      xorHash(sum, left.hash.entry)
+      count++
+      if left.Right != nil {
+        xorHash(sum, left.Right.hash.subtree)
+        count += left.Right.hash.count
+      }
+      left = left.Left
+    } else {
+      left = left.Right
+    }
+  }
+  for right := node.Right; right != nil; {
+    if upper.admitsBelow(right.Key, cmp) {
+      xorHash(sum, right.hash.entry)
+      count++
+      if right.Left != nil {
+        xorHash(sum, right.Left.hash.subtree)
+        count += right.Left.hash.count
+      }
+      right = right.Right
+    } else {
+      right = right.Left
+    }
+  }
+  return sum, count - me.unhashExpired(sum, lower, upper)
+}
+
+// XOR the entry hashes of the expired nodes between the bounds out of sum, returning their number.
+func (me *Tree) unhashExpired(sum []byte, lower keyBound, upper keyBound) int {
+  if me.ttl == nil { return 0 }
+  cmp := me.Comparator()
+  count := 0
+  for _, entry := range me.ttl.expiredEntries() {
+    if !lower.admitsAbove(entry.key, cmp) || !upper.admitsBelow(entry.key, cmp) { continue }
+    node := me.root.find(entry.key, cmp)
+    if node == nil { continue }
+    xorHash(sum, node.hash.entry)
+    count++
+  }
+  return count
+}
+
+// Return the topmost node between the bounds, or nil if there is none.
+func (me *Tree) topmost(lower keyBound, upper keyBound) *Node {
+  cmp := me.Comparator()
+  node := me.root
+  for node != nil {
+    if !lower.admitsAbove(node.Key, cmp) {
+      node = node.Right
+    } else if !upper.admitsBelow(node.Key, cmp) {
+      node = node.Left
+    } else {
+      return node
+    }
+  }
+  return nil
+}
+
+// Ranges holding at most this many entries between both trees are compared entry by entry
+const hashDiffLeafSize = 16
+
+// Return the keys whose entries differ between this tree and other, in key order: keys in only one of
+// the trees, and keys whose values hash differently. Only ranges whose hashes differ are descended into,
+// so the cost depends on the number of differences rather than the size of the trees. Both trees must
+// have hashing enabled with the same HashOptions and the same Comparator.
+func (me *Tree) DiffByHash(other *Tree) ([]Comparable, error) {
+  if me.hasher == nil || other.hasher == nil { return nil, ErrHashingDisabled }
+  if me.hasher.size != other.hasher.size { return nil, fmt.Errorf("binarytree: cannot diff hashes of %d and %d bytes", me.hasher.size, other.hasher.size) }
+  d := &hashDiff{ a: me, b: other, cmp: me.Comparator(), changed: []Comparable{} }
+  d.diff(keyBound{}, keyBound{})
+  return d.changed, nil
+}
+
+type hashDiff struct {
+  a *Tree
+  b *Tree
+  cmp Comparator
+  changed []Comparable
+}
+
+func (me *hashDiff) diff(lower keyBound, upper keyBound) {
+  hashA, countA := me.a.rangeHash(lower, upper)
+  hashB, countB := me.b.rangeHash(lower, upper)
+  if countA == countB && bytes.Equal(hashA, hashB) { return }
+  if countA + countB <= hashDiffLeafSize {
+    me.compareEntries(lower, upper)
+    return
+  }
+  // Split the range at the topmost node of the tree with more entries in it, which has at least one
+  tree := me.a
+  if countB > countA { tree = me.b }
+  split := tree.topmost(lower, upper).Key
+  me.diff(lower, keyBound{ key: split, inclusive: false })
+  me.compareEntries(keyBound{ key: split, inclusive: true }, keyBound{ key: split, inclusive: true })
+  me.diff(keyBound{ key: split, inclusive: false }, upper)
+}
+
+// Record the keys between the bounds whose entries differ, by merging the two trees' entries in order.
+func (me *hashDiff) compareEntries(lower keyBound, upper keyBound) {
+  nodesA, nodesB := []*Node{}, []*Node{}
+  me.a.walkBounded(func(node *Node) { nodesA = append(nodesA, node) }, lower, upper)
+  me.b.walkBounded(func(node *Node) { nodesB = append(nodesB, node) }, lower, upper)
+  i, j := 0, 0
+  for i < len(nodesA) || j < len(nodesB) {
+    switch {
+      case j == len(nodesB) || (i < len(nodesA) && me.cmp(nodesA[i].Key, nodesB[j].Key) < 0):
+        me.changed = append(me.changed, nodesA[i].Key)
+        i++
+      case i == len(nodesA) || me.cmp(nodesA[i].Key, nodesB[j].Key) > 0:
+        me.changed = append(me.changed, nodesB[j].Key)
+        j++
+      default:
+        if !bytes.Equal(nodesA[i].hash.entry, nodesB[j].hash.entry) { me.changed = append(me.changed, nodesA[i].Key) }
+        i++
+        j++
+    }
+  }
+}
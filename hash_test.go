@@ -0,0 +1,193 @@
+package binarytree
+
+import (
+  "crypto/md5"
+  "math/rand"
+  "strconv"
+  "testing"
+  "time"
+  "github.com/stretchr/testify/assert"
+)
+
+func TestRootHash(t *testing.T) {
+  _, err := NewTree().RootHash()
+  assert.Equal(t, ErrHashingDisabled, err)
+
+  empty := NewTree()
+  empty.EnableHashing(nil)
+  hash, err := empty.RootHash()
+  assert.Nil(t, err)
+  assert.Equal(t, make([]byte, 32), hash)
+
+  // Trees with the same entries have the same hash whatever their shape
+  sequential := getTestTreeWithKeys(getTestKeys(100, false))
+  sequential.EnableHashing(nil)
+  random := getTestTreeWithKeys(getTestKeys(100, true))
+  random.EnableHashing(nil)
+  hashA, _ := sequential.RootHash()
+  hashB, _ := random.RootHash()
+  assert.Equal(t, hashA, hashB)
+
+  random.Set(IntKey(50), -1)
+  hashB, _ = random.RootHash()
+  assert.NotEqual(t, hashA, hashB)
+  random.Set(IntKey(50), 50)
+  hashB, _ = random.RootHash()
+  assert.Equal(t, hashA, hashB)
+
+  random.Clear(IntKey(50))
+  hashB, _ = random.RootHash()
+  assert.NotEqual(t, hashA, hashB)
+}
+
+func TestHashMaintained(t *testing.T) {
+  tree := getTestTreeWithKeys(getTestKeys(200, true))
+  tree.EnableHashing(nil)
+  assert.NoError(t, tree.Validate())
+  r := rand.New(rand.NewSource(1))
+  for i:=0; i<1000; i++ {
+    key := IntKey(r.Intn(300))
+    if r.Intn(3) == 0 {
+      tree.Clear(key)
+    } else {
+      tree.Set(key, r.Intn(10))
+    }
+  }
+  assert.NoError(t, tree.Validate())
+
+  tree.Balance()
+  assert.NoError(t, tree.Validate())
+  copied := tree.Copy()
+  assert.NoError(t, copied.Validate())
+  hashA, _ := tree.RootHash()
+  hashB, _ := copied.RootHash()
+  assert.Equal(t, hashA, hashB)
+
+  // Small batches are journaled and large ones rebuild the tree
+  for _, size := range []int{ 5, 500 } {
+    batch := NewBatch()
+    for i:=0; i<size; i++ {
+      key := IntKey(r.Intn(1000))
+      if r.Intn(3) == 0 {
+        batch.Clear(key)
+      } else {
+        batch.Set(key, r.Intn(10))
+      }
+    }
+    assert.Nil(t, tree.Apply(batch))
+    assert.NoError(t, tree.Validate())
+  }
+
+  // The same entries hashed from scratch have the same hash
+  rebuilt := NewTree()
+  tree.Walk(func(key Comparable, value interface{}) { rebuilt.Set(key, value) }, true)
+  rebuilt.EnableHashing(nil)
+  hashA, _ = tree.RootHash()
+  hashB, _ = rebuilt.RootHash()
+  assert.Equal(t, hashA, hashB)
+
+  // Changing a value directly is reported as a stale hash
+  tree.GetNode(tree.root.Key).Value = "changed"
+  err := tree.Validate()
+  assert.Error(t, err)
+  assert.Equal(t, ProblemHash, err.(*ValidationError).Problems[0].Kind)
+}
+
+func TestHashExpiry(t *testing.T) {
+  tree, clock := NewTree(), newFakeClock()
+  tree.SetClock(clock.Now)
+  tree.EnableHashing(nil)
+  for _, key := range getTestKeys(50, true) { tree.SetWithTTL(IntKey(key), key, time.Duration(1+key%2) * time.Minute) }
+  clock.Advance(90 * time.Second)
+  _, count, err := tree.RangeHash(nil, nil)
+  assert.Nil(t, err)
+  assert.Equal(t, 25, count)
+  assert.NoError(t, tree.Validate())
+}
+
+func TestRangeHash(t *testing.T) {
+  tree := getTestTreeWithKeys(getTestKeys(100, true))
+  tree.EnableHashing(nil)
+  for _, r := range [][2]int{ { 0, 99 }, { 10, 20 }, { 50, 50 }, { -5, 3 }, { 97, 200 }, { 60, 40 } } {
+    expected := NewTree()
+    tree.WalkRange(func(key Comparable, value interface{}) { expected.Set(key, value) }, IntKey(r[0]), IntKey(r[1]), true)
+    expected.EnableHashing(nil)
+    expectedHash, expectedCount, _ := expected.RangeHash(nil, nil)
+    hash, count, err := tree.RangeHash(IntKey(r[0]), IntKey(r[1]))
+    assert.Nil(t, err)
+    assert.Equal(t, expectedCount, count, r)
+    assert.Equal(t, expectedHash, hash, r)
+  }
+
+  _, count, _ := tree.RangeHash(nil, IntKey(9))
+  assert.Equal(t, 10, count)
+  _, count, _ = tree.RangeHash(IntKey(90), nil)
+  assert.Equal(t, 10, count)
+}
+
+func TestDiffByHash(t *testing.T) {
+  a := getTestTreeWithKeys(getTestKeys(1000, true))
+  a.EnableHashing(nil)
+  b := getTestTreeWithKeys(getTestKeys(1000, false))
+  b.EnableHashing(nil)
+  changed, err := a.DiffByHash(b)
+  assert.Nil(t, err)
+  assert.Equal(t, []Comparable{}, changed)
+
+  b.Set(IntKey(5), -1)
+  b.Clear(IntKey(500))
+  b.Set(IntKey(1500), 1500)
+  a.Set(IntKey(-1), -1)
+  a.Set(IntKey(999), -1)
+  changed, err = a.DiffByHash(b)
+  assert.Nil(t, err)
+  assert.Equal(t, []Comparable{ IntKey(-1), IntKey(5), IntKey(500), IntKey(999), IntKey(1500) }, changed)
+  changed, _ = b.DiffByHash(a)
+  assert.Equal(t, []Comparable{ IntKey(-1), IntKey(5), IntKey(500), IntKey(999), IntKey(1500) }, changed)
+
+  // Against an empty tree every key differs
+  empty := NewTree()
+  empty.EnableHashing(nil)
+  changed, _ = a.DiffByHash(empty)
+  assert.Equal(t, 1001, len(changed))
+
+  _, err = a.DiffByHash(NewTree())
+  assert.Equal(t, ErrHashingDisabled, err)
+}
+
+func TestHashOptions(t *testing.T) {
+  encoded := 0
+  options := &HashOptions{
+    Hash: md5.New,
+    KeyEncoder: func(key Comparable) []byte { encoded++; return []byte(strconv.Itoa(int(key.(IntKey)))) },
+    ValueEncoder: func(value interface{}) []byte { return []byte(value.(string)) },
+  }
+  a := NewTree()
+  a.Set(IntKey(1), "one")
+  a.Set(IntKey(2), "two")
+  a.EnableHashing(options)
+  assert.Equal(t, 2, encoded)
+  hash, _ := a.RootHash()
+  assert.Equal(t, md5.Size, len(hash))
+
+  b := NewTree()
+  b.EnableHashing(options)
+  b.Set(IntKey(2), "two")
+  b.Set(IntKey(1), "one")
+  hashB, _ := b.RootHash()
+  assert.Equal(t, hash, hashB)
+
+  c := getTestTreeWithKeys([]int{ 1, 2 })
+  c.EnableHashing(nil)
+  _, err := a.DiffByHash(c)
+  assert.Error(t, err)
+}
+
+func BenchmarkDiffByHash(b *testing.B) {
+  treeA := getTestTreeWithKeys(benchmarkKeys(100000, "Random"))
+  treeA.EnableHashing(nil)
+  treeB := treeA.Copy()
+  for i:=0; i<10; i++ { treeB.Set(IntKey(i * 10000), -1) }
+  b.ResetTimer()
+  for i:=0; i<b.N; i++ { treeA.DiffByHash(treeB) }
+}
@@ -6,6 +6,7 @@ type Node struct {
   Right *Node
   Key Comparable
   Value interface{}
+  hash *nodeHash
 }
 
 // Return a new empty node
@@ -28,9 +29,14 @@ func (me *Node) Copy() *Node {
 
 // Find and return the node with the supplied key in this subtree. Return nil if not found.
 func (me *Node) Find(key Comparable) *Node {
+  return me.find(key, DefaultComparator)
+}
+
+func (me *Node) find(key Comparable, cmp Comparator) *Node {
   for me!=nil {
-    if key.EqualTo(me.Key) { return me }
-    if key.LessThan(me.Key) {
+    c := cmp(key, me.Key)
+    if c == 0 { return me }
+    if c < 0 {
       me = me.Left
     } else {
       me = me.Right
@@ -44,10 +50,15 @@ func (me *Node) Find(key Comparable) *Node {
 // 2. If the node is found and it is not the root node, return: node, [node..., root]
 // 2. If the node is not found, return: nearestNode, [node..., root]
 func (me *Node) FindNearest(key Comparable) (*Node, []*Node) {
+  return me.findNearest(key, DefaultComparator)
+}
+
+func (me *Node) findNearest(key Comparable, cmp Comparator) (*Node, []*Node) {
   stack := []*Node{}
   for {
-    if me.Key.EqualTo(key) { return me, stack }
-    if key.LessThan(me.Key) {
+    c := cmp(key, me.Key)
+    if c == 0 { return me, stack }
+    if c < 0 {
       if me.Left == nil { return me, stack }
       stack = append(stack, me) 
       me = me.Left
@@ -62,19 +73,23 @@ func (me *Node) FindNearest(key Comparable) (*Node, []*Node) {
 // Find and return the node with the largest key smaller than the supplied key, i.e.
 // the next smallest node. If there is no smaller node, return nil.
 func (me *Node) Previous(key Comparable) *Node {
-  node, stack := me.FindNearest(key)
-  if !node.Key.EqualTo(key) {
-    if node.Key.LessThan(key) { return node }
+  return me.previous(key, DefaultComparator)
+}
+
+func (me *Node) previous(key Comparable, cmp Comparator) *Node {
+  node, stack := me.findNearest(key, cmp)
+  if cmp(node.Key, key) != 0 {
+    if cmp(node.Key, key) < 0 { return node }
     if len(stack) == 0 { return nil }
     for i:=len(stack)-1; i>=0; i-- {
-      if stack[i].Key.LessThan(key) { return stack[i] }
+      if cmp(stack[i].Key, key) < 0 { return stack[i] }
     }
     return nil
   }
   if node.Left == nil {
     if len(stack) == 0 { return nil }
     for i:=len(stack)-1; i>=0; i-- {
-      if stack[i].Key.LessThan(key) { return stack[i] }
+      if cmp(stack[i].Key, key) < 0 { return stack[i] }
     }
     return nil
   }
@@ -84,19 +99,23 @@ func (me *Node) Previous(key Comparable) *Node {
 // Find and return the node with the smallest key larger than the supplied key, i.e.
 // the next largest node. If there is no larger node, return nil.
 func (me *Node) Next(key Comparable) *Node {
-  node, stack := me.FindNearest(key)
-  if !node.Key.EqualTo(key) {
-    if node.Key.GreaterThan(key) { return node }
+  return me.next(key, DefaultComparator)
+}
+
+func (me *Node) next(key Comparable, cmp Comparator) *Node {
+  node, stack := me.findNearest(key, cmp)
+  if cmp(node.Key, key) != 0 {
+    if cmp(node.Key, key) > 0 { return node }
     if len(stack) == 0 { return nil }
     for i:=len(stack)-1; i>=0; i-- {
-      if stack[i].Key.GreaterThan(key) { return stack[i] }
+      if cmp(stack[i].Key, key) > 0 { return stack[i] }
     }
     // return nil        // This actually isn't possible.
   }
   if node.Right == nil {
     if len(stack) == 0 { return nil }
     for i:=len(stack)-1; i>=0; i-- {
-      if stack[i].Key.GreaterThan(key) { return stack[i] }
+      if cmp(stack[i].Key, key) > 0 { return stack[i] }
     }
     return nil
   }
@@ -105,9 +124,13 @@ func (me *Node) Next(key Comparable) *Node {
 
 // Add an existing node to this node's subtree
 func (me *Node) Add(node *Node) *Node {
+  return me.add(node, DefaultComparator)
+}
+
+func (me *Node) add(node *Node, cmp Comparator) *Node {
   current := me
   for {
-    if node.Key.LessThan(current.Key) {
+    if cmp(node.Key, current.Key) < 0 {
       if current.Left == nil {
         current.Left = node
         return node
@@ -125,7 +148,12 @@ func (me *Node) Add(node *Node) *Node {
 
 // Remove a node from this node's subtree
 func (me *Node) Remove(key Comparable) *Node {
-  if me.Key.EqualTo(key) {
+  return me.remove(key, DefaultComparator)
+}
+
+func (me *Node) remove(key Comparable, cmp Comparator) *Node {
+  c := cmp(key, me.Key)
+  if c == 0 {
     // We are the node being removed
     // Leaf node. Return nil
     if me.Left == nil && me.Right == nil { return nil }
@@ -137,19 +165,19 @@ func (me *Node) Remove(key Comparable) *Node {
     oldMe := me
     me = me.Left
     oldMe.Left = nil
-    if oldMe.Right!=nil { me.Add(oldMe.Right) }
+    if oldMe.Right!=nil { me.add(oldMe.Right, cmp) }
     oldMe.Left = nil
     oldMe.Right = nil
   } else {
     // Walk the tree recursively calling Remove, set
     // each side to the return of Remove.
-    if key.LessThan(me.Key) {
+    if c < 0 {
       if me.Left != nil {
-        me.Left = me.Left.Remove(key)
+        me.Left = me.Left.remove(key, cmp)
       } 
     } else {
       if me.Right != nil {
-        me.Right = me.Right.Remove(key)
+        me.Right = me.Right.remove(key, cmp)
       } 
     }
   }
@@ -158,24 +186,28 @@ func (me *Node) Remove(key Comparable) *Node {
 
 // Balance this node's subtree, returning the new root node.
 func (me *Node) Balance() *Node {
+  return me.balance(DefaultComparator)
+}
+
+func (me *Node) balance(cmp Comparator) *Node {
   var steps int = (me.DepthRight() - me.DepthLeft())/2
   for steps != 0 {
     if steps > 0 {
       oldMe := me
       me = me.Right
       oldMe.Right = nil
-      me.Add(oldMe)
+      me.add(oldMe, cmp)
       steps--
     } else {
       oldMe := me
       me = me.Left
       oldMe.Left = nil
-      me.Add(oldMe)
+      me.add(oldMe, cmp)
       steps++
     }
   }
-  if me.Left!=nil { me.Left = me.Left.Balance() }
-  if me.Right!=nil { me.Right = me.Right.Balance() }
+  if me.Left!=nil { me.Left = me.Left.balance(cmp) }
+  if me.Right!=nil { me.Right = me.Right.balance(cmp) }
   return me
 }
 
@@ -193,22 +225,70 @@ func (me *Node) WalkBackward(iterator func(me *Node)) {
   if me.Left!=nil { me.Left.WalkBackward(iterator) }
 }
 
+// Call iterator for each node in this node's subtree in the supplied direction until iterator returns false.
+// Return false if the walk was stopped by iterator.
+func (me *Node) walk(iterator func(me *Node) bool, forward bool) bool {
+  first, second := me.Left, me.Right
+  if !forward { first, second = me.Right, me.Left }
+  if first!=nil && !first.walk(iterator, forward) { return false }
+  if !iterator(me) { return false }
+  if second!=nil && !second.walk(iterator, forward) { return false }
+  return true
+}
+
 // Call iterator for each node with a key in the range from, to in this node's subtree in order, low to high
-//
-// BUG: Inefficient. Walks whole tree.
 func (me *Node) WalkRangeForward(iterator func(me *Node), from Comparable, to Comparable) {
-  if me.Left!=nil { me.Left.WalkRangeForward(iterator, from, to) }
-  if !me.Key.LessThan(from) && !me.Key.GreaterThan(to) { iterator(me) }
-  if me.Right!=nil { me.Right.WalkRangeForward(iterator, from, to) }
+  me.walkRangeForward(iterator, from, to, DefaultComparator)
+}
+
+func (me *Node) walkRangeForward(iterator func(me *Node), from Comparable, to Comparable, cmp Comparator) {
+  lower, upper := cmp(me.Key, from), cmp(me.Key, to)
+  if me.Left!=nil && lower > 0 { me.Left.walkRangeForward(iterator, from, to, cmp) }
+  if lower >= 0 && upper <= 0 { iterator(me) }
+  if me.Right!=nil && upper < 0 { me.Right.walkRangeForward(iterator, from, to, cmp) }
 }
 
 // Call iterator for each node with a key in the range from, to in this node's subtree in reverse order, high to low
-//
-// BUG: Inefficient. Walks whole tree.
 func (me *Node) WalkRangeBackward(iterator func(me *Node), from Comparable, to Comparable) {
-  if me.Right!=nil { me.Right.WalkRangeBackward(iterator, from, to) }
-  if !me.Key.LessThan(from) && !me.Key.GreaterThan(to) { iterator(me) }
-  if me.Left!=nil { me.Left.WalkRangeBackward(iterator, from, to) }
+  me.walkRangeBackward(iterator, from, to, DefaultComparator)
+}
+
+func (me *Node) walkRangeBackward(iterator func(me *Node), from Comparable, to Comparable, cmp Comparator) {
+  lower, upper := cmp(me.Key, from), cmp(me.Key, to)
+  if me.Right!=nil && upper < 0 { me.Right.walkRangeBackward(iterator, from, to, cmp) }
+  if lower >= 0 && upper <= 0 { iterator(me) }
+  if me.Left!=nil && lower > 0 { me.Left.walkRangeBackward(iterator, from, to, cmp) }
+}
+
+// Call iterator for each node with a key in the range from, to in this node's subtree, in the supplied direction,
+// until iterator returns false. Return false if the walk was stopped by iterator.
+func (me *Node) walkRange(iterator func(me *Node) bool, from Comparable, to Comparable, cmp Comparator, forward bool) bool {
+  lower, upper := cmp(me.Key, from), cmp(me.Key, to)
+  first, second := me.Left, me.Right
+  descendFirst, descendSecond := lower > 0, upper < 0
+  if !forward { first, second, descendFirst, descendSecond = me.Right, me.Left, upper < 0, lower > 0 }
+  if first!=nil && descendFirst && !first.walkRange(iterator, from, to, cmp, forward) { return false }
+  if lower >= 0 && upper <= 0 && !iterator(me) { return false }
+  if second!=nil && descendSecond && !second.walkRange(iterator, from, to, cmp, forward) { return false }
+  return true
+}
+
+// Call iterator for each node with a key beginning with prefix in this node's subtree, in the
+// supplied direction, until iterator returns false. Subtrees that cannot hold the prefix are skipped.
+// Return false if the walk was stopped by iterator.
+func (me *Node) walkPrefix(iterator func(me *Node) bool, prefix Prefixable, cmp Comparator, forward bool) bool {
+  c := cmp(me.Key, prefix)
+  key, ok := me.Key.(Prefixable)
+  match := ok && key.HasPrefix(prefix)
+  // Keys below the prefix can only have matches to their right, keys past it only to their left.
+  first, second := me.Left, me.Right
+  if !forward { first, second = me.Right, me.Left }
+  visitFirst, visitSecond := match || c > 0, match || c < 0
+  if !forward { visitFirst, visitSecond = visitSecond, visitFirst }
+  if first!=nil && visitFirst && !first.walkPrefix(iterator, prefix, cmp, forward) { return false }
+  if match && !iterator(me) { return false }
+  if second!=nil && visitSecond && !second.walkPrefix(iterator, prefix, cmp, forward) { return false }
+  return true
 }
 
 // Return the left-most (smallest key) node in this node's subtree
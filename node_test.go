@@ -1,6 +1,7 @@
 package binarytree
 
 import (
+  "math/rand"
   "testing"
   "github.com/stretchr/testify/assert"
 )
@@ -402,3 +403,18 @@ func getTestTreeBalanced(factor int) *Node {
   root := getTestTreeLeftUnbalanced(factor)
   return root.Balance()
 }
+
+// Return the keys 0..n-1, in order or shuffled into the same order on every run
+func getTestKeys(n int, shuffled bool) []int {
+  keys := make([]int, n)
+  for i := range keys { keys[i] = i }
+  if shuffled { rand.New(rand.NewSource(int64(n))).Shuffle(n, func(i, j int) { keys[i], keys[j] = keys[j], keys[i] }) }
+  return keys
+}
+
+// Return a tree holding the supplied keys in the order given, each with itself as its value
+func getTestTreeWithKeys(keys []int) *Tree {
+  tree := NewTree()
+  for _, key := range keys { tree.Set(IntKey(key), key) }
+  return tree
+}
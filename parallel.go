@@ -0,0 +1,140 @@
+package binarytree
+
+import(
+  "runtime"
+  "sync"
+  "sync/atomic"
+)
+
+// Mapper is a func that computes a result for a key and value, as called by Tree.ParallelMapReduce
+type Mapper func(key Comparable, value interface{}) (interface{}, error)
+
+// Reducer is a func that receives the results of a Mapper in key order, as called by Tree.ParallelMapReduce
+type Reducer func(key Comparable, result interface{}) error
+
+// The number of pieces the key space is split into for each worker, so that uneven work evens out
+const parallelPiecesPerWorker = 4
+
+// Call fn for every key and value in the tree, from up to workers goroutines at once. Calls are not
+// made in key order. If fn returns an error, no further calls are started and the first error is
+// returned. workers <= 0 uses runtime.GOMAXPROCS(0). The tree must not be changed during the walk.
+//
+// Before any worker starts, the nodes in range are collected in order in a single pass, taking time
+// and memory proportional to their number, and cut into pieces of equal size whatever the shape of
+// the tree. The same applies to ParallelWalkRange, ParallelMapReduce and ParallelMapReduceRange.
+func (me *Tree) ParallelWalk(workers int, fn ErrorIterator) error {
+  return me.parallel(workers, errorIteratorMapper(fn), nil, nil, nil)
+}
+
+// Call fn for every key and value between the two keys, inclusive, as ParallelWalk does. Either key
+// may be nil for an open range.
+func (me *Tree) ParallelWalkRange(workers int, fn ErrorIterator, from Comparable, to Comparable) error {
+  return me.parallel(workers, errorIteratorMapper(fn), nil, from, to)
+}
+
+// Call mapper for every key and value in the tree, from up to workers goroutines at once, and pass
+// each result to reducer in key order from the calling goroutine. reducer runs while later keys are
+// still being mapped. If mapper or reducer returns an error, no further calls are started and the
+// first error is returned. The tree must not be changed during the walk.
+func (me *Tree) ParallelMapReduce(workers int, mapper Mapper, reducer Reducer) error {
+  return me.parallel(workers, mapper, reducer, nil, nil)
+}
+
+// Call mapper and reducer for every key and value between the two keys, inclusive, as ParallelMapReduce
+// does. Either key may be nil for an open range.
+func (me *Tree) ParallelMapReduceRange(workers int, mapper Mapper, reducer Reducer, from Comparable, to Comparable) error {
+  return me.parallel(workers, mapper, reducer, from, to)
+}
+
+func errorIteratorMapper(fn ErrorIterator) Mapper {
+  return func(key Comparable, value interface{}) (interface{}, error) { return nil, fn(key, value) }
+}
+
+// parallelPiece is a run of consecutive nodes in key order, walked by a single worker
+type parallelPiece struct {
+  nodes []*Node
+  results []interface{}
+  keys []Comparable
+  done chan struct{}
+}
+
+// parallelWalk holds the state shared by the workers of a parallel walk
+type parallelWalk struct {
+  stopped atomic.Bool
+  once sync.Once
+  err error
+}
+
+// Record the first error and stop the walk.
+func (me *parallelWalk) fail(err error) {
+  me.once.Do(func() {
+    me.err = err
+    me.stopped.Store(true)
+  })
+}
+
+// Split the nodes into up to target pieces of equal size, keeping them in key order.
+func splitNodes(nodes []*Node, target int) []*parallelPiece {
+  if target > len(nodes) { target = len(nodes) }
+  pieces := make([]*parallelPiece, target)
+  for i := range pieces {
+    pieces[i] = &parallelPiece{ nodes: nodes[i * len(nodes) / target:(i+1) * len(nodes) / target] }
+  }
+  return pieces
+}
+
+func (me *Tree) parallel(workers int, mapper Mapper, reducer Reducer, from Comparable, to Comparable) error {
+  if workers <= 0 { workers = runtime.GOMAXPROCS(0) }
+  nodes := []*Node{}
+  me.walkBounded(func(node *Node) { nodes = append(nodes, node) }, keyBound{ key: from, inclusive: true }, keyBound{ key: to, inclusive: true })
+  walk := &parallelWalk{}
+  pieces := splitNodes(nodes, workers * parallelPiecesPerWorker)
+
+  queue := make(chan *parallelPiece, len(pieces))
+  for _, piece := range pieces {
+    piece.done = make(chan struct{})
+    queue <- piece
+  }
+  close(queue)
+
+  wg := &sync.WaitGroup{}
+  for i:=0; i<workers; i++ {
+    wg.Add(1)
+    go func() {
+      defer wg.Done()
+      for piece := range queue {
+        for _, node := range piece.nodes {
+          if walk.stopped.Load() { break }
+          result, err := mapper(node.Key, node.Value)
+          if err != nil {
+            walk.fail(err)
+            break
+          }
+          if reducer != nil {
+            piece.keys = append(piece.keys, node.Key)
+            piece.results = append(piece.results, result)
+          }
+        }
+        close(piece.done)
+      }
+    }()
+  }
+
+  if reducer != nil {
+    reduce:
+    for _, piece := range pieces {
+      <-piece.done
+      if walk.stopped.Load() { break }
+      for i, key := range piece.keys {
+        if err := reducer(key, piece.results[i]); err != nil {
+          walk.fail(err)
+          break reduce
+        }
+      }
+      // Let the results be collected as soon as they are reduced
+      piece.keys, piece.results = nil, nil
+    }
+  }
+  wg.Wait()
+  return walk.err
+}
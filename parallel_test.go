@@ -0,0 +1,163 @@
+package binarytree
+
+import (
+  "errors"
+  "sync"
+  "sync/atomic"
+  "testing"
+  "time"
+  "github.com/stretchr/testify/assert"
+)
+
+func TestParallelWalk(t *testing.T) {
+  tree := getTestTreeWithKeys(getTestKeys(1000, true))
+  mutex := &sync.Mutex{}
+  seen := map[int]int{}
+  err := tree.ParallelWalk(4, func(key Comparable, value interface{}) error {
+    mutex.Lock()
+    defer mutex.Unlock()
+    seen[int(key.(IntKey))] += value.(int) + 1
+    return nil
+  })
+  assert.Nil(t, err)
+  assert.Equal(t, 1000, len(seen))
+  for key, count := range seen { assert.Equal(t, key+1, count) }
+
+  assert.Nil(t, NewTree().ParallelWalk(4, func(key Comparable, value interface{}) error { return errors.New("called") }))
+}
+
+func TestParallelWalkConcurrent(t *testing.T) {
+  tree := getTestTreeWithKeys(getTestKeys(1000, true))
+  // Each call waits until four calls are running at once
+  running := int32(0)
+  release := make(chan struct{})
+  var once sync.Once
+  err := tree.ParallelWalk(4, func(key Comparable, value interface{}) error {
+    if atomic.AddInt32(&running, 1) == 4 { once.Do(func() { close(release) }) }
+    select {
+      case <-release:
+      case <-time.After(5 * time.Second): return errors.New("workers did not run concurrently")
+    }
+    return nil
+  })
+  assert.Nil(t, err)
+}
+
+func TestParallelWalkRange(t *testing.T) {
+  // Sequential keys make a degenerate tree
+  tree := NewTree()
+  for i:=0; i<500; i++ { tree.Set(IntKey(i), i) }
+  count := int32(0)
+  err := tree.ParallelWalkRange(0, func(key Comparable, value interface{}) error {
+    k := int(key.(IntKey))
+    if k < 100 || k > 399 { return errors.New("key out of range") }
+    atomic.AddInt32(&count, 1)
+    return nil
+  }, IntKey(100), IntKey(399))
+  assert.Nil(t, err)
+  assert.Equal(t, int32(300), count)
+}
+
+func TestParallelWalkError(t *testing.T) {
+  tree := getTestTreeWithKeys(getTestKeys(10000, true))
+  failure := errors.New("failure")
+  calls := int32(0)
+  err := tree.ParallelWalk(4, func(key Comparable, value interface{}) error {
+    atomic.AddInt32(&calls, 1)
+    if key == IntKey(100) { return failure }
+    return nil
+  })
+  assert.Equal(t, failure, err)
+  // The other workers stop early
+  assert.True(t, atomic.LoadInt32(&calls) < 10000)
+}
+
+func TestParallelMapReduce(t *testing.T) {
+  tree := getTestTreeWithKeys(getTestKeys(1000, true))
+  keys := []Comparable{}
+  results := []interface{}{}
+  err := tree.ParallelMapReduce(8, func(key Comparable, value interface{}) (interface{}, error) {
+    return value.(int) * 2, nil
+  }, func(key Comparable, result interface{}) error {
+    keys = append(keys, key)
+    results = append(results, result)
+    return nil
+  })
+  assert.Nil(t, err)
+  assert.Equal(t, 1000, len(keys))
+  for i, key := range keys {
+    assert.Equal(t, IntKey(i), key)
+    assert.Equal(t, i*2, results[i])
+  }
+
+  keys = []Comparable{}
+  err = tree.ParallelMapReduceRange(3, func(key Comparable, value interface{}) (interface{}, error) {
+    return nil, nil
+  }, func(key Comparable, result interface{}) error {
+    keys = append(keys, key)
+    return nil
+  }, IntKey(10), IntKey(14))
+  assert.Nil(t, err)
+  assert.Equal(t, []Comparable{ IntKey(10), IntKey(11), IntKey(12), IntKey(13), IntKey(14) }, keys)
+}
+
+func TestParallelMapReduceErrors(t *testing.T) {
+  tree := getTestTreeWithKeys(getTestKeys(1000, true))
+  failure := errors.New("failure")
+  reduced := 0
+  err := tree.ParallelMapReduce(4, func(key Comparable, value interface{}) (interface{}, error) {
+    return value, nil
+  }, func(key Comparable, result interface{}) error {
+    reduced++
+    if key == IntKey(10) { return failure }
+    return nil
+  })
+  assert.Equal(t, failure, err)
+  assert.Equal(t, 11, reduced)
+
+  reduced = 0
+  err = tree.ParallelMapReduce(4, func(key Comparable, value interface{}) (interface{}, error) {
+    if key == IntKey(500) { return nil, failure }
+    return value, nil
+  }, func(key Comparable, result interface{}) error {
+    reduced++
+    return nil
+  })
+  assert.Equal(t, failure, err)
+  assert.True(t, reduced < 1000)
+}
+
+func TestParallelSplit(t *testing.T) {
+  nodes := []*Node{}
+  for i:=0; i<100; i++ { nodes = append(nodes, &Node{ Key: IntKey(i) }) }
+  pieces := splitNodes(nodes, 8)
+  assert.Equal(t, 8, len(pieces))
+  keys := []Comparable{}
+  for _, piece := range pieces {
+    assert.True(t, len(piece.nodes) == 12 || len(piece.nodes) == 13)
+    for _, node := range piece.nodes { keys = append(keys, node.Key) }
+  }
+  for i, key := range keys { assert.Equal(t, IntKey(i), key) }
+
+  // There are never more pieces than nodes
+  assert.Equal(t, 3, len(splitNodes(nodes[:3], 8)))
+  assert.Equal(t, 0, len(splitNodes(nil, 8)))
+}
+
+func TestParallelWalkOpenRange(t *testing.T) {
+  tree := NewTree()
+  for i:=0; i<100; i++ { tree.Set(IntKey(i), i) }
+  for _, c := range []struct{ from Comparable; to Comparable; count int32 }{
+    { nil, IntKey(9), 10 },
+    { IntKey(90), nil, 10 },
+    { nil, nil, 100 },
+  } {
+    count := int32(0)
+    err := tree.ParallelWalkRange(4, func(key Comparable, value interface{}) error {
+      atomic.AddInt32(&count, 1)
+      return nil
+    }, c.from, c.to)
+    assert.Nil(t, err)
+    assert.Equal(t, c.count, count)
+  }
+}
@@ -0,0 +1,224 @@
+package binarytree
+
+import(
+  "bytes"
+  "encoding/gob"
+  "fmt"
+  "io"
+  "reflect"
+  "sync"
+)
+
+// ReconcileStats describes a run of Reconcile
+type ReconcileStats struct {
+  // The number of request and response exchanges.
+  Rounds int
+  // The number of key ranges whose hashes were compared.
+  Ranges int
+  // The number of entries sent by the source.
+  EntriesReceived int
+  // The number of keys set and cleared in the local tree.
+  Set int
+  Cleared int
+  // The number of entries in the local tree once reconciled.
+  Entries int
+  // The bytes written to and read from the connection.
+  BytesSent int64
+  BytesReceived int64
+}
+
+// Ranges the source holds at most this many entries of are sent whole rather than split
+const reconcileLeafSize = 16
+
+// reconcileBound is a keyBound on the wire
+type reconcileBound struct {
+  Key Comparable
+  Inclusive bool
+}
+
+// reconcileRange is the requester's hash of a range of keys
+type reconcileRange struct {
+  Lower reconcileBound
+  Upper reconcileBound
+  Hash []byte
+  Count int
+}
+
+type reconcileEntry struct {
+  Key Comparable
+  Value interface{}
+}
+
+// reconcileResult is the source's answer for one range: it matches, or it holds the entries, or it
+// should be split at the key
+type reconcileResult struct {
+  Match bool
+  Entries []reconcileEntry
+  Split Comparable
+}
+
+type reconcileRequest struct {
+  Ranges []reconcileRange
+}
+
+type reconcileResponse struct {
+  Results []reconcileResult
+}
+
+// Make the tree match the tree of the peer calling ServeReconcile on the other end of conn, transferring
+// only the entries that differ. The peers compare the hashes of key ranges, starting with the whole tree,
+// and split the ranges that differ until they are small enough to send whole, so a round trip is made
+// for each level of the source's tree that holds differences.
+//
+// Both trees must have hashing enabled with the same HashOptions, and the same Comparator. Keys and
+// values are sent with encoding/gob, so their types must be registered with gob.Register if they are not
+// in this package. locker is the tree's lock, held while each round is computed and applied but not for
+// the whole run, so changes made during a run may be missed until the next one.
+func Reconcile(tree *Tree, locker sync.Locker, conn io.ReadWriter) (stats ReconcileStats, err error) {
+  if tree.hasher == nil { return stats, ErrHashingDisabled }
+  counter := &countingReadWriter{ rw: conn }
+  defer func() { stats.BytesSent, stats.BytesReceived = counter.written, counter.read }()
+  encoder, decoder := gob.NewEncoder(counter), gob.NewDecoder(counter)
+
+  pending := []reconcileRange{ {} }
+  for len(pending) > 0 {
+    locker.Lock()
+    tree.expire()
+    for i := range pending {
+      pending[i].Hash, pending[i].Count = tree.rangeHash(pending[i].Lower.bound(), pending[i].Upper.bound())
+    }
+    locker.Unlock()
+    if err := encoder.Encode(&reconcileRequest{ Ranges: pending }); err != nil { return stats, err }
+    response := &reconcileResponse{}
+    if err := decoder.Decode(response); err != nil { return stats, unexpectedEOF(err) }
+    if len(response.Results) != len(pending) { return stats, fmt.Errorf("binarytree: reconcile expected %d results, got %d", len(pending), len(response.Results)) }
+    stats.Rounds++
+    stats.Ranges += len(pending)
+
+    next := []reconcileRange{}
+    batch := NewBatch()
+    locker.Lock()
+    for i, result := range response.Results {
+      r := pending[i]
+      switch {
+        case result.Match:
+        case result.Split != nil:
+          // Each part holds fewer of the source's entries than the range did
+          split := reconcileBound{ Key: result.Split, Inclusive: true }
+          next = append(next,
+            reconcileRange{ Lower: r.Lower, Upper: reconcileBound{ Key: result.Split } },
+            reconcileRange{ Lower: split, Upper: split },
+            reconcileRange{ Lower: reconcileBound{ Key: result.Split }, Upper: r.Upper },
+          )
+        default:
+          stats.EntriesReceived += len(result.Entries)
+          set, cleared := tree.reconcileEntries(batch, r, result.Entries)
+          stats.Set += set
+          stats.Cleared += cleared
+      }
+    }
+    err = tree.Apply(batch)
+    locker.Unlock()
+    if err != nil { return stats, err }
+    pending = next
+  }
+
+  // An empty request ends the exchange
+  if err := encoder.Encode(&reconcileRequest{}); err != nil { return stats, err }
+  locker.Lock()
+  _, stats.Entries = tree.rangeHash(keyBound{}, keyBound{})
+  locker.Unlock()
+  return stats, nil
+}
+
+// Add to the batch the operations that make the range of the tree hold exactly the supplied entries,
+// which are in key order. Return the number of keys set and cleared.
+func (me *Tree) reconcileEntries(batch *Batch, r reconcileRange, entries []reconcileEntry) (int, int) {
+  cmp := me.Comparator()
+  local := []*Node{}
+  me.walkBounded(func(node *Node) { local = append(local, node) }, r.Lower.bound(), r.Upper.bound())
+  set, cleared := 0, 0
+  i, j := 0, 0
+  for i < len(local) || j < len(entries) {
+    c := 0
+    switch {
+      case j == len(entries): c = -1
+      case i == len(local): c = 1
+      default: c = cmp(local[i].Key, entries[j].Key)
+    }
+    switch {
+      case c < 0:
+        batch.Clear(local[i].Key)
+        cleared++
+        i++
+      case c > 0:
+        batch.Set(entries[j].Key, entries[j].Value)
+        set++
+        j++
+      default:
+        if !reflect.DeepEqual(local[i].Value, entries[j].Value) {
+          batch.Set(entries[j].Key, entries[j].Value)
+          set++
+        }
+        i++
+        j++
+    }
+  }
+  return set, cleared
+}
+
+// Answer the requests of a peer calling Reconcile on the other end of conn, making its tree match this
+// one, until it ends the exchange. The tree must have hashing enabled. locker is the tree's lock, held
+// while each request is answered.
+func ServeReconcile(tree *Tree, locker sync.Locker, conn io.ReadWriter) error {
+  if tree.hasher == nil { return ErrHashingDisabled }
+  encoder, decoder := gob.NewEncoder(conn), gob.NewDecoder(conn)
+  for {
+    request := &reconcileRequest{}
+    if err := decoder.Decode(request); err != nil { return unexpectedEOF(err) }
+    if len(request.Ranges) == 0 { return nil }
+    response := &reconcileResponse{ Results: make([]reconcileResult, len(request.Ranges)) }
+    locker.Lock()
+    tree.expire()
+    for i, r := range request.Ranges {
+      response.Results[i] = tree.reconcileRange(r)
+    }
+    locker.Unlock()
+    if err := encoder.Encode(response); err != nil { return err }
+  }
+}
+
+// Return the answer for a range of the requester's tree.
+func (me *Tree) reconcileRange(r reconcileRange) reconcileResult {
+  lower, upper := r.Lower.bound(), r.Upper.bound()
+  hash, count := me.rangeHash(lower, upper)
+  if count == r.Count && bytes.Equal(hash, r.Hash) { return reconcileResult{ Match: true } }
+  if count > reconcileLeafSize { return reconcileResult{ Split: me.topmost(lower, upper).Key } }
+  entries := []reconcileEntry{}
+  me.walkBounded(func(node *Node) { entries = append(entries, reconcileEntry{ Key: node.Key, Value: node.Value }) }, lower, upper)
+  return reconcileResult{ Entries: entries }
+}
+
+// Return the bound, treating a nil key as unbounded.
+func (me reconcileBound) bound() keyBound {
+  return keyBound{ key: me.Key, inclusive: me.Inclusive }
+}
+
+// countingReadWriter counts the bytes read from and written to a stream
+type countingReadWriter struct {
+  rw io.ReadWriter
+  read int64
+  written int64
+}
+
+func (me *countingReadWriter) Read(p []byte) (int, error) {
+  n, err := me.rw.Read(p)
+  me.read += int64(n)
+  return n, err
+}
+
+func (me *countingReadWriter) Write(p []byte) (int, error) {
+  n, err := me.rw.Write(p)
+  me.written += int64(n)
+  return n, err
+}
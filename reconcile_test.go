@@ -0,0 +1,93 @@
+package binarytree
+
+import (
+  "bytes"
+  "encoding/gob"
+  "net"
+  "sync"
+  "testing"
+  "github.com/stretchr/testify/assert"
+)
+
+// Reconcile the requester's tree with the source's over a pipe.
+func reconcilePipe(t *testing.T, requester *Tree, source *Tree) ReconcileStats {
+  requesterConn, sourceConn := net.Pipe()
+  defer requesterConn.Close()
+  served := make(chan error, 1)
+  go func() {
+    served <- ServeReconcile(source, &sync.Mutex{}, sourceConn)
+    sourceConn.Close()
+  }()
+  stats, err := Reconcile(requester, &sync.Mutex{}, requesterConn)
+  assert.Nil(t, err)
+  assert.Nil(t, <-served)
+  return stats
+}
+
+func reconcileTestTrees(n int) (*Tree, *Tree) {
+  keys := getTestKeys(n, true)
+  source := getTestTreeWithKeys(keys)
+  source.EnableHashing(nil)
+  // The requester has the same entries in a differently shaped tree
+  reversed := make([]int, n)
+  for i, key := range keys { reversed[n-1-i] = key }
+  requester := getTestTreeWithKeys(reversed)
+  requester.EnableHashing(nil)
+  return requester, source
+}
+
+func TestReconcile(t *testing.T) {
+  requester, source := reconcileTestTrees(10000)
+  source.Set(IntKey(17), "changed")
+  source.Set(IntKey(20000), 20000)
+  source.Clear(IntKey(5000))
+  requester.Set(IntKey(-3), -3)
+  requester.Set(IntKey(9999), "stale")
+
+  stats := reconcilePipe(t, requester, source)
+  assert.Equal(t, []Change{}, Diff(requester, source, nil))
+  assert.NoError(t, requester.Validate())
+  assert.Equal(t, 3, stats.Set)
+  assert.Equal(t, 2, stats.Cleared)
+  assert.Equal(t, 10000, stats.Entries)
+  assert.True(t, stats.Rounds > 1)
+
+  // Far less is transferred than the whole tree
+  full := &bytes.Buffer{}
+  entries := []reconcileEntry{}
+  source.Walk(func(key Comparable, value interface{}) { entries = append(entries, reconcileEntry{ Key: key, Value: value }) }, true)
+  assert.Nil(t, gob.NewEncoder(full).Encode(entries))
+  assert.True(t, stats.BytesSent > 0 && stats.BytesReceived > 0)
+  transferred := stats.BytesSent + stats.BytesReceived
+  assert.True(t, transferred * 10 < int64(full.Len()), "transferred %d bytes, tree is %d bytes", transferred, full.Len())
+
+  // Trees that already match take one round
+  stats = reconcilePipe(t, requester, source)
+  assert.Equal(t, 1, stats.Rounds)
+  assert.Equal(t, 0, stats.Set + stats.Cleared)
+}
+
+func TestReconcileEmpty(t *testing.T) {
+  requester, source := reconcileTestTrees(1000)
+  empty := NewTree()
+  empty.EnableHashing(nil)
+  stats := reconcilePipe(t, empty, source)
+  assert.Equal(t, 1000, stats.Set)
+  assert.Equal(t, []Change{}, Diff(empty, source, nil))
+
+  empty = NewTree()
+  empty.EnableHashing(nil)
+  stats = reconcilePipe(t, requester, empty)
+  assert.Equal(t, 1000, stats.Cleared)
+  assert.Equal(t, 0, stats.Entries)
+  assert.Nil(t, requester.root)
+}
+
+func TestReconcileHashingDisabled(t *testing.T) {
+  a, b := net.Pipe()
+  defer a.Close()
+  defer b.Close()
+  _, err := Reconcile(NewTree(), &sync.Mutex{}, a)
+  assert.Equal(t, ErrHashingDisabled, err)
+  assert.Equal(t, ErrHashingDisabled, ServeReconcile(NewTree(), &sync.Mutex{}, b))
+}
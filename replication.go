@@ -0,0 +1,389 @@
+package binarytree
+
+import(
+  "encoding/binary"
+  "encoding/gob"
+  "errors"
+  "fmt"
+  "io"
+  "net"
+  "sync"
+)
+
+// RecordOp identifies the kind of a replication Record
+type RecordOp int
+
+const (
+  // Set Key to Value.
+  RecordSet RecordOp = iota
+  // Clear Key.
+  RecordClear
+  // Begin a snapshot of the leader's tree at Seq. The RecordSet records up to the next RecordSnapshotEnd
+  // hold every entry, and replace the follower's tree.
+  RecordSnapshot
+  // End a snapshot.
+  RecordSnapshotEnd
+)
+
+// Return the name of the record op
+func (me RecordOp) String() string {
+  switch me {
+    case RecordSet: return "set"
+    case RecordClear: return "clear"
+    case RecordSnapshot: return "snapshot"
+    case RecordSnapshotEnd: return "snapshot end"
+  }
+  return "unknown"
+}
+
+// Record is a single entry in a replication stream. Seq is the number of mutations the leader's tree
+// had seen once the record's mutation was made, so the first mutation is 1.
+type Record struct {
+  Seq uint64
+  Op RecordOp
+  Key Comparable
+  Value interface{}
+}
+
+// RecordEncoder writes records to a stream, as returned by Codec.NewEncoder
+type RecordEncoder interface {
+  Encode(record *Record) error
+}
+
+// RecordDecoder reads records from a stream, as returned by Codec.NewDecoder
+type RecordDecoder interface {
+  Decode(record *Record) error
+}
+
+// Codec encodes the records of a replication stream. Each stream has its own encoder and decoder,
+// so codecs may keep state, such as type information, between records.
+type Codec interface {
+  NewEncoder(w io.Writer) RecordEncoder
+  NewDecoder(r io.Reader) RecordDecoder
+}
+
+// GobCodec is a Codec using encoding/gob, and is the default. The key types in this package are
+// registered with gob; other key and value types held in an interface must be registered with gob.Register.
+type GobCodec struct {}
+
+func init() {
+  for _, key := range []Comparable{ IntKey(0), StringKey(""), ByteSliceKey{}, LexicalByteSliceKey{}, Int64Key(0), Uint64Key(0), Float64Key(0), UUIDKey{}, TupleKey{}, TimeKey{} } {
+    gob.Register(key)
+  }
+}
+
+// Return a gob encoder writing to w.
+func (me GobCodec) NewEncoder(w io.Writer) RecordEncoder {
+  return &gobRecordEncoder{ encoder: gob.NewEncoder(w) }
+}
+
+// Return a gob decoder reading from r.
+func (me GobCodec) NewDecoder(r io.Reader) RecordDecoder {
+  return &gobRecordDecoder{ decoder: gob.NewDecoder(r) }
+}
+
+type gobRecordEncoder struct {
+  encoder *gob.Encoder
+}
+
+func (me *gobRecordEncoder) Encode(record *Record) error {
+  return me.encoder.Encode(record)
+}
+
+type gobRecordDecoder struct {
+  decoder *gob.Decoder
+}
+
+func (me *gobRecordDecoder) Decode(record *Record) error {
+  *record = Record{}
+  return me.decoder.Decode(record)
+}
+
+// ErrLeaderClosed is returned by Leader.WriteSnapshot once the leader has been closed.
+var ErrLeaderClosed = errors.New("binarytree: leader is closed")
+
+// LeaderOptions controls a Leader. The zero value is valid.
+type LeaderOptions struct {
+  // The codec for records. Defaults to GobCodec.
+  Codec Codec
+  // The minimum number of recent records kept for followers that fall behind or reconnect. A follower
+  // that needs an older record is sent a snapshot instead. Defaults to 1024.
+  Retain int
+}
+
+// The default number of records a Leader retains
+const defaultLeaderRetain = 1024
+
+// Leader records every mutation of a tree in a sequence numbered log, and streams snapshots and the log
+// to Followers.
+//
+// TTLs are not replicated: SetWithTTL is logged as a plain set, and an expired entry is logged as a
+// clear only once the leader removes it, by a later write, Expire or a reaper. Until then followers
+// still return entries the leader hides, so run a reaper on the leader when using TTLs.
+type Leader struct {
+  tree *Tree
+  locker sync.Locker
+  codec Codec
+  retain int
+  sub *Subscription
+  mutex sync.Mutex
+  changed *sync.Cond
+  // log holds the records after base, up to seq
+  log []Record
+  base uint64
+  seq uint64
+  closed bool
+}
+
+// Return a new Leader logging the mutations of the tree from now on. locker is the tree's lock, held
+// while taking snapshots. opts may be nil.
+func NewLeader(tree *Tree, locker sync.Locker, opts *LeaderOptions) *Leader {
+  if opts == nil { opts = &LeaderOptions{} }
+  leader := &Leader{ tree: tree, locker: locker, codec: opts.Codec, retain: opts.Retain }
+  if leader.codec == nil { leader.codec = GobCodec{} }
+  if leader.retain <= 0 { leader.retain = defaultLeaderRetain }
+  leader.changed = sync.NewCond(&leader.mutex)
+  locker.Lock()
+  leader.sub = tree.Watch(nil, nil, &WatchOptions{ Callback: leader.record })
+  locker.Unlock()
+  return leader
+}
+
+// Append a record for the event to the log. Called from the mutating call, with locker held.
+func (me *Leader) record(event Event) {
+  me.mutex.Lock()
+  defer me.mutex.Unlock()
+  me.seq++
+  record := Record{ Seq: me.seq, Op: RecordSet, Key: event.Key, Value: event.NewValue }
+  if event.Op == EventClear { record.Op = RecordClear }
+  me.log = append(me.log, record)
+  // Trim the log in bulk, so records are not copied for every mutation
+  if len(me.log) >= 2 * me.retain {
+    trim := len(me.log) - me.retain
+    me.log = append([]Record{}, me.log[trim:]...)
+    me.base += uint64(trim)
+  }
+  me.changed.Broadcast()
+}
+
+// Return the sequence number of the most recent mutation.
+func (me *Leader) Seq() uint64 {
+  me.mutex.Lock()
+  defer me.mutex.Unlock()
+  return me.seq
+}
+
+// Stop logging mutations. Serve calls waiting for records return nil. Close may be called more than once.
+func (me *Leader) Close() {
+  me.sub.Close()
+  me.mutex.Lock()
+  defer me.mutex.Unlock()
+  me.closed = true
+  me.changed.Broadcast()
+}
+
+// Write a snapshot of the tree to w, and return the sequence number it was taken at. A follower can
+// load it with Follower.ReadSnapshot and then resume from that sequence number with Follower.Follow.
+func (me *Leader) WriteSnapshot(w io.Writer) (uint64, error) {
+  return me.writeSnapshot(me.codec.NewEncoder(w))
+}
+
+func (me *Leader) writeSnapshot(encoder RecordEncoder) (uint64, error) {
+  me.locker.Lock()
+  // Remove expired entries first, so they are logged as clears rather than sent in the snapshot
+  me.tree.expire()
+  me.mutex.Lock()
+  closed, seq := me.closed, me.seq
+  me.mutex.Unlock()
+  if closed {
+    me.locker.Unlock()
+    return 0, ErrLeaderClosed
+  }
+  // Walk a copy, so the tree can change while the snapshot is written
+  snapshot := me.tree.Copy()
+  me.locker.Unlock()
+
+  if err := encoder.Encode(&Record{ Seq: seq, Op: RecordSnapshot }); err != nil { return 0, err }
+  var err error
+  if snapshot.root != nil {
+    snapshot.root.walk(func(node *Node) bool {
+      err = encoder.Encode(&Record{ Seq: seq, Op: RecordSet, Key: node.Key, Value: node.Value })
+      return err == nil
+    }, true)
+  }
+  if err != nil { return 0, err }
+  if err := encoder.Encode(&Record{ Seq: seq, Op: RecordSnapshotEnd }); err != nil { return 0, err }
+  return seq, nil
+}
+
+// Stream records to a follower over rw until the leader is closed or writing fails. The follower's
+// handshake says where it is up to; if the leader no longer retains the records it needs, or it has
+// no state, it is sent a snapshot first. Return nil if the leader was closed.
+//
+// A follower that disconnects is only noticed when the next record is written to it.
+func (me *Leader) Serve(rw io.ReadWriter) error {
+  resume, next, err := readHandshake(rw)
+  if err != nil { return err }
+  encoder := me.codec.NewEncoder(rw)
+  for {
+    me.mutex.Lock()
+    for !me.closed && resume && next == me.seq + 1 { me.changed.Wait() }
+    if me.closed {
+      me.mutex.Unlock()
+      return nil
+    }
+    // Followers ahead of the leader have followed some other leader
+    if !resume || next <= me.base || next > me.seq + 1 {
+      me.mutex.Unlock()
+      seq, err := me.writeSnapshot(encoder)
+      if err == ErrLeaderClosed { return nil }
+      if err != nil { return err }
+      resume, next = true, seq + 1
+      continue
+    }
+    records := append([]Record{}, me.log[next - me.base - 1:]...)
+    me.mutex.Unlock()
+    for i := range records {
+      if err := encoder.Encode(&records[i]); err != nil { return err }
+    }
+    next += uint64(len(records))
+  }
+}
+
+// Accept connections from followers on the listener, serving each from its own goroutine and closing it
+// when Serve returns. Return the listener's error once it is closed.
+func (me *Leader) Accept(listener net.Listener) error {
+  for {
+    conn, err := listener.Accept()
+    if err != nil { return err }
+    go func() {
+      defer conn.Close()
+      me.Serve(conn)
+    }()
+  }
+}
+
+// FollowerOptions controls a Follower. The zero value is valid.
+type FollowerOptions struct {
+  // The codec for records, which must match the leader's. Defaults to GobCodec.
+  Codec Codec
+}
+
+// Follower applies the records streamed by a Leader to a tree, keeping it a replica of the leader's tree
+type Follower struct {
+  tree *Tree
+  locker sync.Locker
+  codec Codec
+  mutex sync.Mutex
+  seq uint64
+  synced bool
+}
+
+// Return a new Follower applying records to the tree. locker is the tree's lock, held while applying
+// each record. opts may be nil.
+func NewFollower(tree *Tree, locker sync.Locker, opts *FollowerOptions) *Follower {
+  if opts == nil { opts = &FollowerOptions{} }
+  follower := &Follower{ tree: tree, locker: locker, codec: opts.Codec }
+  if follower.codec == nil { follower.codec = GobCodec{} }
+  return follower
+}
+
+// Return true and the sequence number of the last record applied, or false if the follower has not
+// yet loaded a snapshot.
+func (me *Follower) Seq() (bool, uint64) {
+  me.mutex.Lock()
+  defer me.mutex.Unlock()
+  return me.synced, me.seq
+}
+
+// Load a snapshot written by Leader.WriteSnapshot, replacing the contents of the tree.
+func (me *Follower) ReadSnapshot(r io.Reader) error {
+  decoder := me.codec.NewDecoder(r)
+  record := &Record{}
+  if err := decoder.Decode(record); err != nil { return err }
+  if record.Op != RecordSnapshot { return fmt.Errorf("binarytree: expected a snapshot, got a %v record", record.Op) }
+  return me.readSnapshot(decoder, record.Seq)
+}
+
+// Read the entries of a snapshot after its RecordSnapshot record, then replace the tree's contents with them.
+func (me *Follower) readSnapshot(decoder RecordDecoder, seq uint64) error {
+  snapshot := NewTreeWithComparator(me.tree.Comparator())
+  batch := NewBatch()
+  for {
+    record := &Record{}
+    if err := decoder.Decode(record); err != nil { return unexpectedEOF(err) }
+    if record.Op == RecordSnapshotEnd { break }
+    if record.Op != RecordSet { return fmt.Errorf("binarytree: unexpected %v record in snapshot", record.Op) }
+    batch.Set(record.Key, record.Value)
+  }
+  if err := snapshot.Apply(batch); err != nil { return err }
+  me.locker.Lock()
+  defer me.locker.Unlock()
+  // Patching in only the differences keeps the tree's watchers and hashes up to date
+  if err := Patch(me.tree, Diff(me.tree, snapshot, nil)); err != nil { return err }
+  me.mutex.Lock()
+  me.seq, me.synced = seq, true
+  me.mutex.Unlock()
+  return nil
+}
+
+// Send a handshake to the leader over rw, then apply the records it streams until the stream ends.
+// Return nil if the leader closed the stream between records, otherwise the error that stopped it.
+// Follow can be called again after an error to resume from the last record applied.
+func (me *Follower) Follow(rw io.ReadWriter) error {
+  synced, seq := me.Seq()
+  if err := writeHandshake(rw, synced, seq + 1); err != nil { return err }
+  decoder := me.codec.NewDecoder(rw)
+  for {
+    record := &Record{}
+    if err := decoder.Decode(record); err != nil {
+      if err == io.EOF { return nil }
+      return err
+    }
+    switch record.Op {
+      case RecordSnapshot:
+        if err := me.readSnapshot(decoder, record.Seq); err != nil { return err }
+      case RecordSet, RecordClear:
+        me.apply(record)
+      default:
+        return fmt.Errorf("binarytree: unexpected %v record", record.Op)
+    }
+  }
+}
+
+// Apply a mutation record to the tree.
+func (me *Follower) apply(record *Record) {
+  me.locker.Lock()
+  defer me.locker.Unlock()
+  if record.Op == RecordSet {
+    me.tree.Set(record.Key, record.Value)
+  } else {
+    me.tree.Clear(record.Key)
+  }
+  me.mutex.Lock()
+  me.seq = record.Seq
+  me.mutex.Unlock()
+}
+
+// Return io.ErrUnexpectedEOF in place of io.EOF, for streams that ended part way through.
+func unexpectedEOF(err error) error {
+  if err == io.EOF { return io.ErrUnexpectedEOF }
+  return err
+}
+
+// The handshake is a flag byte, 1 if the follower has state, and the next sequence number it needs
+const handshakeSize = 9
+
+func writeHandshake(w io.Writer, resume bool, next uint64) error {
+  handshake := make([]byte, handshakeSize)
+  if resume { handshake[0] = 1 }
+  binary.BigEndian.PutUint64(handshake[1:], next)
+  _, err := w.Write(handshake)
+  return err
+}
+
+func readHandshake(r io.Reader) (bool, uint64, error) {
+  handshake := make([]byte, handshakeSize)
+  if _, err := io.ReadFull(r, handshake); err != nil { return false, 0, err }
+  return handshake[0] == 1, binary.BigEndian.Uint64(handshake[1:]), nil
+}
@@ -0,0 +1,208 @@
+package binarytree
+
+import (
+  "bytes"
+  "net"
+  "sync"
+  "testing"
+  "time"
+  "github.com/stretchr/testify/assert"
+)
+
+// replica is a tree and the lock guarding it, as used by a Leader or Follower
+type replica struct {
+  tree *Tree
+  mutex sync.Mutex
+}
+
+func (me *replica) set(key int, value interface{}) {
+  me.mutex.Lock()
+  defer me.mutex.Unlock()
+  me.tree.Set(IntKey(key), value)
+}
+
+func (me *replica) clear(key int) {
+  me.mutex.Lock()
+  defer me.mutex.Unlock()
+  me.tree.Clear(IntKey(key))
+}
+
+// Return the changes between the two replicas' trees.
+func (me *replica) diff(other *replica) []Change {
+  me.mutex.Lock()
+  defer me.mutex.Unlock()
+  other.mutex.Lock()
+  defer other.mutex.Unlock()
+  return Diff(me.tree, other.tree, nil)
+}
+
+func newReplica(n int) *replica {
+  r := &replica{ tree: NewTree() }
+  for i:=0; i<n; i++ { r.tree.Set(IntKey(i), i) }
+  return r
+}
+
+// Wait until the follower has applied the record with the sequence number.
+func waitForSeq(t *testing.T, follower *Follower, seq uint64) {
+  assert.Eventually(t, func() bool {
+    synced, applied := follower.Seq()
+    return synced && applied >= seq
+  }, 5 * time.Second, time.Millisecond)
+}
+
+func TestReplicationPipe(t *testing.T) {
+  primary := newReplica(100)
+  leader := NewLeader(primary.tree, &primary.mutex, nil)
+  secondary := newReplica(0)
+  secondary.tree.Set(IntKey(1000), "stale")
+  follower := NewFollower(secondary.tree, &secondary.mutex, nil)
+
+  leaderConn, followerConn := net.Pipe()
+  served, followed := make(chan error, 1), make(chan error, 1)
+  go func() { served <- leader.Serve(leaderConn) }()
+  go func() { followed <- follower.Follow(followerConn) }()
+
+  // The follower starts from a snapshot, which replaces its contents
+  waitForSeq(t, follower, 0)
+  assert.Equal(t, []Change{}, primary.diff(secondary))
+
+  for i:=0; i<50; i++ {
+    primary.set(i, -i)
+    primary.clear(i + 50)
+  }
+  primary.set(200, "new")
+  assert.Equal(t, uint64(101), leader.Seq())
+  waitForSeq(t, follower, 101)
+  assert.Equal(t, []Change{}, primary.diff(secondary))
+
+  leader.Close()
+  assert.Nil(t, <-served)
+  leaderConn.Close()
+  assert.Nil(t, <-followed)
+}
+
+func TestReplicationResume(t *testing.T) {
+  primary := newReplica(10)
+  leader := NewLeader(primary.tree, &primary.mutex, &LeaderOptions{ Retain: 10 })
+  defer leader.Close()
+
+  // Bootstrap from a snapshot
+  buffer := &bytes.Buffer{}
+  seq, err := leader.WriteSnapshot(buffer)
+  assert.Nil(t, err)
+  assert.Equal(t, uint64(0), seq)
+  secondary := newReplica(0)
+  follower := NewFollower(secondary.tree, &secondary.mutex, nil)
+  assert.Nil(t, follower.ReadSnapshot(buffer))
+  assert.Equal(t, []Change{}, primary.diff(secondary))
+
+  primary.set(20, 20)
+  primary.set(21, 21)
+
+  // Resuming from the snapshot's sequence number sends only the tail
+  follow := func() {
+    leaderConn, followerConn := net.Pipe()
+    go func() {
+      leader.Serve(leaderConn)
+      leaderConn.Close()
+    }()
+    done := make(chan error, 1)
+    go func() { done <- follower.Follow(followerConn) }()
+    waitForSeq(t, follower, leader.Seq())
+    followerConn.Close()
+    <-done
+  }
+  follow()
+  assert.Equal(t, []Change{}, primary.diff(secondary))
+
+  // A follower that falls further behind than the leader retains gets a new snapshot
+  for i:=0; i<30; i++ { primary.set(i, "again") }
+  primary.clear(5)
+  follow()
+  assert.Equal(t, []Change{}, primary.diff(secondary))
+  _, applied := follower.Seq()
+  assert.Equal(t, uint64(33), applied)
+}
+
+func TestReplicationExpiry(t *testing.T) {
+  primary := newReplica(3)
+  clock := newFakeClock()
+  primary.tree.SetClock(clock.Now)
+  leader := NewLeader(primary.tree, &primary.mutex, nil)
+  defer leader.Close()
+  primary.tree.SetWithTTL(IntKey(10), 10, time.Second)
+  primary.tree.SetWithTTL(IntKey(11), 11, time.Minute)
+  clock.Advance(time.Second)
+
+  // The expired entry is removed and logged as a clear before the snapshot is taken
+  buffer := &bytes.Buffer{}
+  seq, err := leader.WriteSnapshot(buffer)
+  assert.Nil(t, err)
+  assert.Equal(t, uint64(3), seq)
+  secondary := newReplica(0)
+  assert.Nil(t, NewFollower(secondary.tree, &secondary.mutex, nil).ReadSnapshot(buffer))
+  assert.Equal(t, []Change{}, primary.diff(secondary))
+  found, _ := secondary.tree.Get(IntKey(10))
+  assert.False(t, found)
+  assert.Equal(t, 4, secondary.tree.Stats().Count)
+}
+
+func TestReplicationTCP(t *testing.T) {
+  primary := newReplica(500)
+  leader := NewLeader(primary.tree, &primary.mutex, nil)
+  listener, err := net.Listen("tcp", "127.0.0.1:0")
+  assert.Nil(t, err)
+  accepted := make(chan error, 1)
+  go func() { accepted <- leader.Accept(listener) }()
+
+  followers := []*Follower{}
+  secondaries := []*replica{}
+  done := make(chan error, 3)
+  for i:=0; i<3; i++ {
+    secondary := newReplica(0)
+    follower := NewFollower(secondary.tree, &secondary.mutex, nil)
+    conn, err := net.Dial("tcp", listener.Addr().String())
+    assert.Nil(t, err)
+    go func() { done <- follower.Follow(conn) }()
+    followers, secondaries = append(followers, follower), append(secondaries, secondary)
+  }
+
+  for i:=0; i<1000; i++ {
+    if i % 3 == 0 {
+      primary.clear(i / 2)
+    } else {
+      primary.set(i, i * i)
+    }
+  }
+  for i, follower := range followers {
+    waitForSeq(t, follower, leader.Seq())
+    assert.Equal(t, []Change{}, primary.diff(secondaries[i]))
+  }
+
+  // Closing the leader ends every stream cleanly
+  leader.Close()
+  for range followers { assert.Nil(t, <-done) }
+  listener.Close()
+  assert.Error(t, <-accepted)
+}
+
+func TestReplicationCodec(t *testing.T) {
+  buffer := &bytes.Buffer{}
+  encoder := GobCodec{}.NewEncoder(buffer)
+  records := []Record{
+    { Seq: 1, Op: RecordSet, Key: StringKey("a"), Value: 1 },
+    { Seq: 2, Op: RecordClear, Key: TupleKey(MustEncodeTuple("b", 2)) },
+    { Seq: 3, Op: RecordSet, Key: Float64Key(1.5), Value: "x" },
+    { Seq: 4, Op: RecordSet, Key: NewTimeKey(time.Date(2020, 1, 2, 3, 4, 5, 6, time.UTC)), Value: "y" },
+  }
+  for i := range records { assert.Nil(t, encoder.Encode(&records[i])) }
+  decoder := GobCodec{}.NewDecoder(buffer)
+  for _, expected := range records {
+    record := &Record{}
+    assert.Nil(t, decoder.Decode(record))
+    assert.Equal(t, expected, *record)
+  }
+
+  assert.Error(t, NewFollower(NewTree(), &sync.Mutex{}, nil).ReadSnapshot(bytes.NewReader(nil)))
+  assert.Equal(t, "snapshot end", RecordSnapshotEnd.String())
+}
@@ -0,0 +1,398 @@
+// Package server exposes a binarytree.Tree as a REST key-value service over HTTP, with JSON bodies.
+//
+//   GET    /keys/{key}                 Return the entry for the key
+//   PUT    /keys/{key}                 Set the key to the JSON value in the body
+//   DELETE /keys/{key}                 Clear the key
+//   GET    /range?from=&to=&reverse=&limit=&token=
+//                                      Return a page of entries between from and to, inclusive
+//   GET    /first, /last               Return the lowest or highest entry
+//   GET    /next?key=, /prev?key=      Return the entry after or before the key
+//   GET    /stats                      Return the tree's Stats
+//
+// Entries are returned as {"key": "...", "value": ...}, with keys formatted by the server's KeyCodec,
+// and errors as {"error": "..."}.
+package server
+
+import(
+  "context"
+  "encoding/base64"
+  "encoding/json"
+  "errors"
+  "fmt"
+  "net/http"
+  "strconv"
+  "sync"
+
+  "github.com/tomdionysus/binarytree"
+)
+
+// KeyCodec converts keys to and from their form in URLs and responses
+type KeyCodec interface {
+  Parse(s string) (binarytree.Comparable, error)
+  Format(key binarytree.Comparable) (string, error)
+}
+
+// StringKeyCodec is a KeyCodec for binarytree.StringKey keys
+var StringKeyCodec KeyCodec = stringKeyCodec{}
+
+// IntKeyCodec is a KeyCodec for binarytree.IntKey keys, in decimal
+var IntKeyCodec KeyCodec = intKeyCodec{}
+
+// UUIDKeyCodec is a KeyCodec for binarytree.UUIDKey keys, in their canonical form
+var UUIDKeyCodec KeyCodec = uuidKeyCodec{}
+
+type stringKeyCodec struct {}
+
+func (me stringKeyCodec) Parse(s string) (binarytree.Comparable, error) { return binarytree.StringKey(s), nil }
+
+func (me stringKeyCodec) Format(key binarytree.Comparable) (string, error) {
+  s, ok := key.(binarytree.StringKey)
+  if !ok { return "", keyTypeError(key, s) }
+  return string(s), nil
+}
+
+type intKeyCodec struct {}
+
+func (me intKeyCodec) Parse(s string) (binarytree.Comparable, error) {
+  i, err := strconv.Atoi(s)
+  if err != nil { return nil, err }
+  return binarytree.IntKey(i), nil
+}
+
+func (me intKeyCodec) Format(key binarytree.Comparable) (string, error) {
+  i, ok := key.(binarytree.IntKey)
+  if !ok { return "", keyTypeError(key, i) }
+  return strconv.Itoa(int(i)), nil
+}
+
+type uuidKeyCodec struct {}
+
+func (me uuidKeyCodec) Parse(s string) (binarytree.Comparable, error) { return binarytree.ParseUUIDKey(s) }
+
+func (me uuidKeyCodec) Format(key binarytree.Comparable) (string, error) {
+  uuid, ok := key.(binarytree.UUIDKey)
+  if !ok { return "", keyTypeError(key, uuid) }
+  return uuid.String(), nil
+}
+
+// Return an error for a key that is not of the codec's type.
+func keyTypeError(key binarytree.Comparable, expected binarytree.Comparable) error {
+  return fmt.Errorf("cannot format %T key %v as %T", key, key, expected)
+}
+
+// Options controls a Server. The zero value is valid.
+type Options struct {
+  // The codec for keys. Defaults to StringKeyCodec.
+  Keys KeyCodec
+  // The tree's lock. Defaults to a lock used only by the server.
+  Locker sync.Locker
+  // The number of entries in a page of /range when no limit is given. Defaults to 100.
+  DefaultLimit int
+  // The largest limit /range accepts. Defaults to 1000.
+  MaxLimit int
+}
+
+// Server is an http.Handler serving a Tree
+type Server struct {
+  tree *binarytree.Tree
+  keys KeyCodec
+  locker sync.Locker
+  defaultLimit int
+  maxLimit int
+  mux *http.ServeMux
+}
+
+// Entry is a key and value, as returned by the server
+type Entry struct {
+  Key string `json:"key"`
+  Value interface{} `json:"value"`
+}
+
+// Page is a page of entries returned by /range. Next is the token for the following page, and is
+// empty on the last page.
+type Page struct {
+  Entries []Entry `json:"entries"`
+  Next string `json:"next,omitempty"`
+}
+
+// Return a new Server for the tree. opts may be nil.
+func New(tree *binarytree.Tree, opts *Options) *Server {
+  if opts == nil { opts = &Options{} }
+  server := &Server{ tree: tree, keys: opts.Keys, locker: opts.Locker, defaultLimit: opts.DefaultLimit, maxLimit: opts.MaxLimit, mux: http.NewServeMux() }
+  if server.keys == nil { server.keys = StringKeyCodec }
+  if server.locker == nil { server.locker = &sync.Mutex{} }
+  if server.maxLimit <= 0 { server.maxLimit = 1000 }
+  if server.defaultLimit <= 0 { server.defaultLimit = 100 }
+  if server.defaultLimit > server.maxLimit { server.defaultLimit = server.maxLimit }
+  server.mux.HandleFunc("GET /keys/{key}", server.get)
+  server.mux.HandleFunc("PUT /keys/{key}", server.put)
+  server.mux.HandleFunc("DELETE /keys/{key}", server.delete)
+  server.mux.HandleFunc("GET /range", server.walkRange)
+  server.mux.HandleFunc("GET /first", server.first)
+  server.mux.HandleFunc("GET /last", server.last)
+  server.mux.HandleFunc("GET /next", server.next)
+  server.mux.HandleFunc("GET /prev", server.previous)
+  server.mux.HandleFunc("GET /stats", server.stats)
+  return server
+}
+
+// Serve the request.
+func (me *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+  me.mux.ServeHTTP(w, r)
+}
+
+// Write a JSON response.
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+  w.Header().Set("Content-Type", "application/json")
+  w.WriteHeader(status)
+  json.NewEncoder(w).Encode(body)
+}
+
+// Write a JSON error response.
+func writeError(w http.ResponseWriter, status int, format string, args ...interface{}) {
+  writeJSON(w, status, map[string]string{ "error": fmt.Sprintf(format, args...) })
+}
+
+// Parse the key, writing a 400 response and returning false if it is invalid.
+func (me *Server) parseKey(w http.ResponseWriter, s string) (binarytree.Comparable, bool) {
+  key, err := me.keys.Parse(s)
+  if err != nil {
+    writeError(w, http.StatusBadRequest, "invalid key %q: %v", s, err)
+    return nil, false
+  }
+  return key, true
+}
+
+// Format the key, writing a 500 response and returning false if the codec cannot format it.
+func (me *Server) formatKey(w http.ResponseWriter, key binarytree.Comparable) (string, bool) {
+  s, err := me.keys.Format(key)
+  if err != nil {
+    writeError(w, http.StatusInternalServerError, "%v", err)
+    return "", false
+  }
+  return s, true
+}
+
+// Write the entry with the supplied status.
+func (me *Server) writeEntry(w http.ResponseWriter, status int, key binarytree.Comparable, value interface{}) {
+  s, ok := me.formatKey(w, key)
+  if !ok { return }
+  writeJSON(w, status, Entry{ Key: s, Value: value })
+}
+
+// Write the entry, or a 404 response if found is false.
+func (me *Server) writeFound(w http.ResponseWriter, found bool, key binarytree.Comparable, value interface{}) {
+  if !found {
+    writeError(w, http.StatusNotFound, "not found")
+    return
+  }
+  me.writeEntry(w, http.StatusOK, key, value)
+}
+
+func (me *Server) get(w http.ResponseWriter, r *http.Request) {
+  key, ok := me.parseKey(w, r.PathValue("key"))
+  if !ok { return }
+  me.locker.Lock()
+  found, value := me.tree.Get(key)
+  me.locker.Unlock()
+  me.writeFound(w, found, key, value)
+}
+
+// Set the key to the JSON value in the body, responding 201 if the key is new and 200 if it was replaced.
+// Numbers are stored as json.Number.
+func (me *Server) put(w http.ResponseWriter, r *http.Request) {
+  key, ok := me.parseKey(w, r.PathValue("key"))
+  if !ok { return }
+  var value interface{}
+  decoder := json.NewDecoder(r.Body)
+  decoder.UseNumber()
+  if err := decoder.Decode(&value); err != nil {
+    writeError(w, http.StatusBadRequest, "invalid JSON value: %v", err)
+    return
+  }
+  me.locker.Lock()
+  existed, _ := me.tree.Get(key)
+  err := me.tree.SetChecked(key, value)
+  me.locker.Unlock()
+  if err != nil {
+    writeError(w, http.StatusBadRequest, "%v", err)
+    return
+  }
+  status := http.StatusCreated
+  if existed { status = http.StatusOK }
+  me.writeEntry(w, status, key, value)
+}
+
+// Clear the key, responding 204, or 404 if it was not present.
+func (me *Server) delete(w http.ResponseWriter, r *http.Request) {
+  key, ok := me.parseKey(w, r.PathValue("key"))
+  if !ok { return }
+  me.locker.Lock()
+  found, _ := me.tree.Get(key)
+  if found { me.tree.Clear(key) }
+  me.locker.Unlock()
+  if !found {
+    writeError(w, http.StatusNotFound, "not found")
+    return
+  }
+  w.WriteHeader(http.StatusNoContent)
+}
+
+func (me *Server) first(w http.ResponseWriter, r *http.Request) {
+  me.locker.Lock()
+  key, value := me.tree.First()
+  me.locker.Unlock()
+  me.writeFound(w, key != nil, key, value)
+}
+
+func (me *Server) last(w http.ResponseWriter, r *http.Request) {
+  me.locker.Lock()
+  key, value := me.tree.Last()
+  me.locker.Unlock()
+  me.writeFound(w, key != nil, key, value)
+}
+
+func (me *Server) next(w http.ResponseWriter, r *http.Request) {
+  key, ok := me.parseKey(w, r.URL.Query().Get("key"))
+  if !ok { return }
+  me.locker.Lock()
+  found, nextKey, value := me.tree.Next(key)
+  me.locker.Unlock()
+  me.writeFound(w, found, nextKey, value)
+}
+
+func (me *Server) previous(w http.ResponseWriter, r *http.Request) {
+  key, ok := me.parseKey(w, r.URL.Query().Get("key"))
+  if !ok { return }
+  me.locker.Lock()
+  found, previousKey, value := me.tree.Previous(key)
+  me.locker.Unlock()
+  me.writeFound(w, found, previousKey, value)
+}
+
+func (me *Server) stats(w http.ResponseWriter, r *http.Request) {
+  me.locker.Lock()
+  treeStats := me.tree.Stats()
+  me.locker.Unlock()
+  writeJSON(w, http.StatusOK, treeStats)
+}
+
+// rangeToken is the state of a /range walk carried by its continuation token. Keys are in their
+// formatted form, and From and To are empty for open bounds.
+type rangeToken struct {
+  From string `json:"f,omitempty"`
+  To string `json:"t,omitempty"`
+  Reverse bool `json:"r,omitempty"`
+  // The last key returned
+  After string `json:"a"`
+}
+
+// Return the token as an opaque string.
+func (me rangeToken) encode() string {
+  data, _ := json.Marshal(me)
+  return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeRangeToken(s string) (rangeToken, error) {
+  token := rangeToken{}
+  data, err := base64.RawURLEncoding.DecodeString(s)
+  if err != nil { return token, err }
+  err = json.Unmarshal(data, &token)
+  return token, err
+}
+
+// Stops a range walk once its page is full
+var errPageFull = errors.New("page full")
+
+// Return a page of the entries between from and to, inclusive, in order or in reverse. Either bound may
+// be omitted. A token from a previous page continues that walk, and the other parameters except limit are ignored.
+func (me *Server) walkRange(w http.ResponseWriter, r *http.Request) {
+  query := r.URL.Query()
+  limit := me.defaultLimit
+  if s := query.Get("limit"); s != "" {
+    var err error
+    limit, err = strconv.Atoi(s)
+    if err != nil || limit <= 0 || limit > me.maxLimit {
+      writeError(w, http.StatusBadRequest, "invalid limit %q: must be between 1 and %d", s, me.maxLimit)
+      return
+    }
+  }
+  token := rangeToken{ From: query.Get("from"), To: query.Get("to") }
+  if s := query.Get("reverse"); s != "" {
+    reverse, err := strconv.ParseBool(s)
+    if err != nil {
+      writeError(w, http.StatusBadRequest, "invalid reverse %q", s)
+      return
+    }
+    token.Reverse = reverse
+  }
+  continued := false
+  if s := query.Get("token"); s != "" {
+    var err error
+    if token, err = decodeRangeToken(s); err != nil {
+      writeError(w, http.StatusBadRequest, "invalid token")
+      return
+    }
+    continued = true
+  }
+
+  var from, to, after binarytree.Comparable
+  var ok bool
+  if token.From != "" {
+    if from, ok = me.parseKey(w, token.From); !ok { return }
+  }
+  if token.To != "" {
+    if to, ok = me.parseKey(w, token.To); !ok { return }
+  }
+  if continued {
+    if after, ok = me.parseKey(w, token.After); !ok { return }
+  }
+
+  keys, values, more := me.collectRange(r.Context(), from, to, after, token.Reverse, limit)
+  // Keys are formatted once the lock is released
+  page := Page{ Entries: []Entry{} }
+  for i, key := range keys {
+    s, ok := me.formatKey(w, key)
+    if !ok { return }
+    page.Entries = append(page.Entries, Entry{ Key: s, Value: values[i] })
+  }
+  if more {
+    token.After = page.Entries[len(page.Entries)-1].Key
+    page.Next = token.encode()
+  }
+  writeJSON(w, http.StatusOK, page)
+}
+
+// Return up to limit keys and values between from and to, inclusive, after the key after if it is not
+// nil, and whether there are more. Open bounds are nil.
+func (me *Server) collectRange(ctx context.Context, from, to, after binarytree.Comparable, reverse bool, limit int) ([]binarytree.Comparable, []interface{}, bool) {
+  me.locker.Lock()
+  defer me.locker.Unlock()
+  // Open bounds are the first and last keys, and a continued walk starts after the last key returned
+  if from == nil { from, _ = me.tree.First() }
+  if to == nil { to, _ = me.tree.Last() }
+  if after != nil {
+    found, start := false, binarytree.Comparable(nil)
+    if reverse {
+      found, start, _ = me.tree.Previous(after)
+      to = start
+    } else {
+      found, start, _ = me.tree.Next(after)
+      from = start
+    }
+    if !found { from, to = nil, nil }
+  }
+  keys, values, more := []binarytree.Comparable{}, []interface{}{}, false
+  if from == nil || to == nil { return keys, values, more }
+  me.tree.WalkRangeContext(ctx, func(key binarytree.Comparable, value interface{}) error {
+    if len(keys) == limit {
+      // There is at least one more entry
+      more = true
+      return errPageFull
+    }
+    keys = append(keys, key)
+    values = append(values, value)
+    return nil
+  }, from, to, !reverse)
+  return keys, values, more
+}
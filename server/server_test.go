@@ -0,0 +1,193 @@
+package server
+
+import (
+  "encoding/json"
+  "io"
+  "net/http"
+  "net/http/httptest"
+  "net/url"
+  "strings"
+  "testing"
+  "github.com/stretchr/testify/assert"
+  "github.com/tomdionysus/binarytree"
+)
+
+// Make a request to the server, returning the status and the decoded JSON body.
+func request(t *testing.T, server *httptest.Server, method string, path string, body string, decoded interface{}) int {
+  req, err := http.NewRequest(method, server.URL + path, strings.NewReader(body))
+  assert.Nil(t, err)
+  resp, err := server.Client().Do(req)
+  assert.Nil(t, err)
+  defer resp.Body.Close()
+  data, err := io.ReadAll(resp.Body)
+  assert.Nil(t, err)
+  if decoded != nil && len(data) > 0 { assert.Nil(t, json.Unmarshal(data, decoded), string(data)) }
+  return resp.StatusCode
+}
+
+func newTestServer(n int, opts *Options) *httptest.Server {
+  tree := binarytree.NewTree()
+  for i:=0; i<n; i++ { tree.Set(binarytree.IntKey(i * 10), i) }
+  if opts == nil { opts = &Options{} }
+  opts.Keys = IntKeyCodec
+  return httptest.NewServer(New(tree, opts))
+}
+
+func TestKeys(t *testing.T) {
+  tree := binarytree.NewTree()
+  server := httptest.NewServer(New(tree, nil))
+  defer server.Close()
+
+  entry := Entry{}
+  assert.Equal(t, http.StatusNotFound, request(t, server, "GET", "/keys/a", "", nil))
+  assert.Equal(t, http.StatusCreated, request(t, server, "PUT", "/keys/a", `{"name":"one","n":1}`, &entry))
+  assert.Equal(t, Entry{ Key: "a", Value: map[string]interface{}{ "name": "one", "n": 1.0 } }, entry)
+  // Numbers are stored exactly
+  _, value := tree.Get(binarytree.StringKey("a"))
+  assert.Equal(t, map[string]interface{}{ "name": "one", "n": json.Number("1") }, value)
+  assert.Equal(t, http.StatusOK, request(t, server, "PUT", "/keys/a", `[1,2]`, nil))
+  entry = Entry{}
+  assert.Equal(t, http.StatusOK, request(t, server, "GET", "/keys/a", "", &entry))
+  assert.Equal(t, Entry{ Key: "a", Value: []interface{}{ 1.0, 2.0 } }, entry)
+
+  // Keys are unescaped from the path
+  assert.Equal(t, http.StatusCreated, request(t, server, "PUT", "/keys/" + url.PathEscape("a b/c"), `"x"`, nil))
+  entry = Entry{}
+  assert.Equal(t, http.StatusOK, request(t, server, "GET", "/keys/a%20b%2Fc", "", &entry))
+  assert.Equal(t, "a b/c", entry.Key)
+
+  assert.Equal(t, http.StatusNoContent, request(t, server, "DELETE", "/keys/a", "", nil))
+  assert.Equal(t, http.StatusNotFound, request(t, server, "DELETE", "/keys/a", "", nil))
+  assert.Equal(t, http.StatusNotFound, request(t, server, "GET", "/keys/a", "", nil))
+
+  errorBody := map[string]string{}
+  assert.Equal(t, http.StatusBadRequest, request(t, server, "PUT", "/keys/b", `{`, &errorBody))
+  assert.Contains(t, errorBody["error"], "invalid JSON value")
+  assert.Equal(t, http.StatusMethodNotAllowed, request(t, server, "POST", "/keys/b", "", nil))
+}
+
+func TestKeyCodec(t *testing.T) {
+  server := newTestServer(3, nil)
+  defer server.Close()
+  errorBody := map[string]string{}
+  assert.Equal(t, http.StatusBadRequest, request(t, server, "GET", "/keys/ten", "", &errorBody))
+  assert.Contains(t, errorBody["error"], `invalid key "ten"`)
+  entry := Entry{}
+  assert.Equal(t, http.StatusOK, request(t, server, "GET", "/keys/20", "", &entry))
+  assert.Equal(t, Entry{ Key: "20", Value: 2.0 }, entry)
+
+  key, err := UUIDKeyCodec.Parse("00112233-4455-6677-8899-aabbccddeeff")
+  assert.Nil(t, err)
+  formatted, err := UUIDKeyCodec.Format(key)
+  assert.Nil(t, err)
+  assert.Equal(t, "00112233-4455-6677-8899-aabbccddeeff", formatted)
+
+  for _, codec := range []KeyCodec{ StringKeyCodec, IntKeyCodec, UUIDKeyCodec } {
+    _, err = codec.Format(binarytree.Float64Key(1))
+    assert.Error(t, err)
+  }
+}
+
+func TestKeyCodecMismatch(t *testing.T) {
+  // The default StringKeyCodec cannot format the IntKeys in the tree
+  tree := binarytree.NewTree()
+  for i:=0; i<3; i++ { tree.Set(binarytree.IntKey(i), i) }
+  server := httptest.NewServer(New(tree, nil))
+  defer server.Close()
+  errorBody := map[string]string{}
+  assert.Equal(t, http.StatusInternalServerError, request(t, server, "GET", "/range", "", &errorBody))
+  assert.Equal(t, "cannot format binarytree.IntKey key 0 as binarytree.StringKey", errorBody["error"])
+  assert.Equal(t, http.StatusInternalServerError, request(t, server, "GET", "/first", "", nil))
+  // The lock was released
+  stats := binarytree.TreeStats{}
+  assert.Equal(t, http.StatusOK, request(t, server, "GET", "/stats", "", &stats))
+  assert.Equal(t, 3, stats.Count)
+}
+
+func TestNavigation(t *testing.T) {
+  server := newTestServer(5, nil)
+  defer server.Close()
+  for _, c := range []struct{ path string; status int; key string }{
+    { "/first", http.StatusOK, "0" },
+    { "/last", http.StatusOK, "40" },
+    { "/next?key=10", http.StatusOK, "20" },
+    { "/next?key=15", http.StatusOK, "20" },
+    { "/next?key=40", http.StatusNotFound, "" },
+    { "/prev?key=10", http.StatusOK, "0" },
+    { "/prev?key=0", http.StatusNotFound, "" },
+  } {
+    entry := Entry{}
+    assert.Equal(t, c.status, request(t, server, "GET", c.path, "", &entry), c.path)
+    assert.Equal(t, c.key, entry.Key, c.path)
+  }
+
+  empty := httptest.NewServer(New(binarytree.NewTree(), nil))
+  defer empty.Close()
+  assert.Equal(t, http.StatusNotFound, request(t, empty, "GET", "/first", "", nil))
+  assert.Equal(t, http.StatusNotFound, request(t, empty, "GET", "/last", "", nil))
+}
+
+// Return the keys of every page of the range, following the continuation tokens.
+func rangeKeys(t *testing.T, server *httptest.Server, query string) ([]string, int) {
+  keys := []string{}
+  pages := 0
+  path := "/range?" + query
+  for {
+    page := Page{}
+    assert.Equal(t, http.StatusOK, request(t, server, "GET", path, "", &page))
+    pages++
+    for _, entry := range page.Entries { keys = append(keys, entry.Key) }
+    if page.Next == "" { return keys, pages }
+    path = "/range?limit=3&token=" + page.Next
+  }
+}
+
+func TestRange(t *testing.T) {
+  server := newTestServer(10, &Options{ DefaultLimit: 4, MaxLimit: 5 })
+  defer server.Close()
+
+  keys, pages := rangeKeys(t, server, "")
+  assert.Equal(t, []string{ "0", "10", "20", "30", "40", "50", "60", "70", "80", "90" }, keys)
+  assert.Equal(t, 3, pages)
+
+  keys, _ = rangeKeys(t, server, "from=15&to=65&limit=2")
+  assert.Equal(t, []string{ "20", "30", "40", "50", "60" }, keys)
+
+  keys, pages = rangeKeys(t, server, "to=50&reverse=true&limit=2")
+  assert.Equal(t, []string{ "50", "40", "30", "20", "10", "0" }, keys)
+  assert.Equal(t, 3, pages)
+
+  // A page that ends exactly at the last entry has no token
+  keys, pages = rangeKeys(t, server, "from=60&limit=4")
+  assert.Equal(t, []string{ "60", "70", "80", "90" }, keys)
+  assert.Equal(t, 1, pages)
+
+  keys, _ = rangeKeys(t, server, "from=95")
+  assert.Equal(t, []string{}, keys)
+
+  for _, query := range []string{ "limit=0", "limit=6", "limit=x", "reverse=maybe", "token=!", "from=x" } {
+    assert.Equal(t, http.StatusBadRequest, request(t, server, "GET", "/range?" + query, "", nil), query)
+  }
+}
+
+func TestRangeTokenAfterChange(t *testing.T) {
+  tree := binarytree.NewTree()
+  for i:=0; i<6; i++ { tree.Set(binarytree.IntKey(i), i) }
+  server := httptest.NewServer(New(tree, &Options{ Keys: IntKeyCodec }))
+  defer server.Close()
+  page := Page{}
+  request(t, server, "GET", "/range?limit=2", "", &page)
+  // The walk continues after the last key returned, even once it has been cleared
+  assert.Equal(t, http.StatusNoContent, request(t, server, "DELETE", "/keys/1", "", nil))
+  next := Page{}
+  request(t, server, "GET", "/range?limit=2&token=" + page.Next, "", &next)
+  assert.Equal(t, []Entry{ { Key: "2", Value: 2.0 }, { Key: "3", Value: 3.0 } }, next.Entries)
+}
+
+func TestStats(t *testing.T) {
+  server := newTestServer(7, nil)
+  defer server.Close()
+  stats := binarytree.TreeStats{}
+  assert.Equal(t, http.StatusOK, request(t, server, "GET", "/stats", "", &stats))
+  assert.Equal(t, 7, stats.Count)
+}
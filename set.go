@@ -0,0 +1,213 @@
+package binarytree
+
+// Set is an ordered set of keys. Keys are held in setNodes, which have no value field, so a Set uses
+// less memory than a Tree with the same keys.
+type Set struct {
+  root *setNode
+  compare Comparator
+  count int
+}
+
+// setNode is a node of a Set's binary tree, holding only a key
+type setNode struct {
+  left *setNode
+  right *setNode
+  key Comparable
+}
+
+// SetIterator is a func that can iterate a set
+type SetIterator func(key Comparable)
+
+// Return a new empty set
+func NewSet() *Set {
+  return &Set{}
+}
+
+// Return a new empty set that orders its keys with the supplied Comparator
+func NewSetWithComparator(cmp Comparator) *Set {
+  return &Set{ compare: cmp }
+}
+
+// Return the Comparator used to order this set's keys.
+func (me *Set) comparator() Comparator {
+  if me.compare == nil { return DefaultComparator }
+  return me.compare
+}
+
+// Add the supplied key to the set. Adding a key that is already a member has no effect.
+func (me *Set) Add(key Comparable) {
+  node := &setNode{ key: key }
+  if me.root == nil {
+    me.root = node
+    me.count++
+    return
+  }
+  cmp := me.comparator()
+  current := me.root
+  for {
+    c := cmp(key, current.key)
+    if c == 0 { return }
+    if c < 0 {
+      if current.left == nil {
+        current.left = node
+        break
+      }
+      current = current.left
+    } else {
+      if current.right == nil {
+        current.right = node
+        break
+      }
+      current = current.right
+    }
+  }
+  me.count++
+}
+
+// Return true if the supplied key is a member of the set.
+func (me *Set) Contains(key Comparable) bool {
+  cmp := me.comparator()
+  for node := me.root; node != nil; {
+    c := cmp(key, node.key)
+    if c == 0 { return true }
+    if c < 0 {
+      node = node.left
+    } else {
+      node = node.right
+    }
+  }
+  return false
+}
+
+// Remove the supplied key from the set.
+func (me *Set) Remove(key Comparable) {
+  cmp := me.comparator()
+  slot := &me.root
+  for *slot != nil {
+    node := *slot
+    c := cmp(key, node.key)
+    if c < 0 {
+      slot = &node.left
+    } else if c > 0 {
+      slot = &node.right
+    } else {
+      // Replace the node with its right subtree hung below the maximum of its left subtree
+      switch {
+        case node.left == nil: *slot = node.right
+        case node.right == nil: *slot = node.left
+        default:
+          *slot = node.left
+          node.left.maximum().right = node.right
+      }
+      me.count--
+      return
+    }
+  }
+}
+
+// Return the number of keys in the set.
+func (me *Set) Len() int {
+  return me.count
+}
+
+// Return the smallest key in the set, or nil if the set is empty.
+func (me *Set) Min() Comparable {
+  if me.root == nil { return nil }
+  return me.root.minimum().key
+}
+
+// Return the largest key in the set, or nil if the set is empty.
+func (me *Set) Max() Comparable {
+  if me.root == nil { return nil }
+  return me.root.maximum().key
+}
+
+// Return a deep copy of the set.
+func (me *Set) Copy() *Set {
+  return &Set{ root: me.root.copy(), compare: me.compare, count: me.count }
+}
+
+// Balance the set.
+func (me *Set) Balance() {
+  keys := make([]Comparable, 0, me.count)
+  me.root.walk(func(node *setNode) bool { keys = append(keys, node.key); return true }, true)
+  me.root = linkSetBalanced(keys)
+}
+
+// Return a balanced subtree of the keys, which are in order.
+func linkSetBalanced(keys []Comparable) *setNode {
+  if len(keys) == 0 { return nil }
+  middle := len(keys) / 2
+  return &setNode{ left: linkSetBalanced(keys[:middle]), right: linkSetBalanced(keys[middle+1:]), key: keys[middle] }
+}
+
+// Iterate the set with the function in the supplied direction
+func (me *Set) Walk(iterator SetIterator, forward bool) {
+  me.root.walk(func(node *setNode) bool { iterator(node.key); return true }, forward)
+}
+
+// Iterate the set for all keys between the two keys, inclusive
+func (me *Set) WalkRange(iterator SetIterator, from Comparable, to Comparable, forward bool) {
+  me.root.walkRange(func(node *setNode) { iterator(node.key) }, from, to, me.comparator(), forward)
+}
+
+// Return true if every key in this set is also a member of the other set.
+func (me *Set) IsSubsetOf(other *Set) bool {
+  if me.count > other.count { return false }
+  subset := true
+  me.root.walk(func(node *setNode) bool {
+    subset = other.Contains(node.key)
+    return subset
+  }, true)
+  return subset
+}
+
+// Return true if every key in the other set is also a member of this set.
+func (me *Set) IsSupersetOf(other *Set) bool {
+  return other.IsSubsetOf(me)
+}
+
+// Return true if both sets contain exactly the same keys.
+func (me *Set) Equal(other *Set) bool {
+  return me.count == other.count && me.IsSubsetOf(other)
+}
+
+// Return a deep copy of this node and its children. The node may be nil.
+func (me *setNode) copy() *setNode {
+  if me == nil { return nil }
+  return &setNode{ left: me.left.copy(), right: me.right.copy(), key: me.key }
+}
+
+// Return the node with the smallest key in this node's subtree.
+func (me *setNode) minimum() *setNode {
+  for me.left != nil { me = me.left }
+  return me
+}
+
+// Return the node with the largest key in this node's subtree.
+func (me *setNode) maximum() *setNode {
+  for me.right != nil { me = me.right }
+  return me
+}
+
+// Call iterator for each node in this node's subtree in the supplied direction, until iterator returns
+// false. The node may be nil. Return false if the walk was stopped by iterator.
+func (me *setNode) walk(iterator func(node *setNode) bool, forward bool) bool {
+  if me == nil { return true }
+  first, second := me.left, me.right
+  if !forward { first, second = me.right, me.left }
+  return first.walk(iterator, forward) && iterator(me) && second.walk(iterator, forward)
+}
+
+// Call iterator for each node in this node's subtree between the two keys, inclusive, in the supplied
+// direction. The node may be nil.
+func (me *setNode) walkRange(iterator func(node *setNode), from Comparable, to Comparable, cmp Comparator, forward bool) {
+  if me == nil { return }
+  lower, upper := cmp(me.key, from), cmp(me.key, to)
+  first, second := me.left, me.right
+  descendFirst, descendSecond := lower > 0, upper < 0
+  if !forward { first, second, descendFirst, descendSecond = me.right, me.left, upper < 0, lower > 0 }
+  if descendFirst { first.walkRange(iterator, from, to, cmp, forward) }
+  if lower >= 0 && upper <= 0 { iterator(me) }
+  if descendSecond { second.walkRange(iterator, from, to, cmp, forward) }
+}
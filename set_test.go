@@ -0,0 +1,173 @@
+package binarytree
+
+import (
+  "testing"
+  "github.com/stretchr/testify/assert"
+)
+
+func TestNewSet(t *testing.T) {
+  x := NewSet()
+
+  assert.Nil(t, x.root)
+  assert.Equal(t, 0, x.Len())
+}
+
+func TestSetAdd(t *testing.T) {
+  set := NewSet()
+
+  set.Add(IntKey(4))
+  set.Add(IntKey(2))
+  set.Add(IntKey(6))
+  set.Add(IntKey(2))
+
+  assert.Equal(t, 3, set.Len())
+  assert.Equal(t, IntKey(4), set.root.key)
+  assert.Equal(t, IntKey(2), set.root.left.key)
+  assert.Equal(t, IntKey(6), set.root.right.key)
+}
+
+func TestSetContains(t *testing.T) {
+  set := NewSet()
+
+  assert.False(t, set.Contains(IntKey(1)))
+
+  set.Add(IntKey(1))
+  set.Add(IntKey(3))
+
+  assert.True(t, set.Contains(IntKey(1)))
+  assert.True(t, set.Contains(IntKey(3)))
+  assert.False(t, set.Contains(IntKey(2)))
+}
+
+func TestSetRemove(t *testing.T) {
+  set := NewSet()
+
+  set.Remove(IntKey(1))
+
+  set.Add(IntKey(1))
+  set.Add(IntKey(2))
+  set.Add(IntKey(3))
+
+  set.Remove(IntKey(2))
+  set.Remove(IntKey(5))
+
+  assert.True(t, set.Contains(IntKey(1)))
+  assert.False(t, set.Contains(IntKey(2)))
+  assert.True(t, set.Contains(IntKey(3)))
+  assert.Equal(t, 2, set.Len())
+
+  // Nodes with two children, including the root
+  set = NewSet()
+  for _, key := range []int{ 4, 2, 6, 1, 3, 5, 7 } { set.Add(IntKey(key)) }
+  set.Remove(IntKey(2))
+  set.Remove(IntKey(4))
+  out := []Comparable{}
+  set.Walk(func(key Comparable) { out = append(out, key) }, true)
+  assert.Equal(t, []Comparable{ IntKey(1), IntKey(3), IntKey(5), IntKey(6), IntKey(7) }, out)
+  assert.Equal(t, 5, set.Len())
+}
+
+func TestSetMinMax(t *testing.T) {
+  set := NewSet()
+
+  assert.Nil(t, set.Min())
+  assert.Nil(t, set.Max())
+
+  set.Add(StringKey("m"))
+  set.Add(StringKey("a"))
+  set.Add(StringKey("z"))
+
+  assert.Equal(t, StringKey("a"), set.Min())
+  assert.Equal(t, StringKey("z"), set.Max())
+}
+
+func TestSetCopy(t *testing.T) {
+  set := NewSet()
+  set.Add(IntKey(1))
+  set.Add(IntKey(2))
+
+  set2 := set.Copy()
+  set2.Remove(IntKey(1))
+
+  assert.True(t, set.Contains(IntKey(1)))
+  assert.False(t, set2.Contains(IntKey(1)))
+}
+
+func TestSetWalk(t *testing.T) {
+  set := NewSet()
+  for i:=7; i>0; i-- { set.Add(IntKey(i)) }
+  set.Balance()
+  assert.Equal(t, IntKey(4), set.root.key)
+  assert.Equal(t, IntKey(2), set.root.left.key)
+  assert.Equal(t, IntKey(6), set.root.right.key)
+
+  out := []int{}
+  set.Walk(func(key Comparable) { out = append(out, key.ValueOf().(int)) }, true)
+  assert.Equal(t, []int{1,2,3,4,5,6,7}, out)
+
+  out = []int{}
+  set.Walk(func(key Comparable) { out = append(out, key.ValueOf().(int)) }, false)
+  assert.Equal(t, []int{7,6,5,4,3,2,1}, out)
+
+  out = []int{}
+  set.WalkRange(func(key Comparable) { out = append(out, key.ValueOf().(int)) }, IntKey(3), IntKey(5), true)
+  assert.Equal(t, []int{3,4,5}, out)
+
+  out = []int{}
+  set.WalkRange(func(key Comparable) { out = append(out, key.ValueOf().(int)) }, IntKey(3), IntKey(5), false)
+  assert.Equal(t, []int{5,4,3}, out)
+}
+
+func TestSetSubsetSuperset(t *testing.T) {
+  empty := NewSet()
+  small := NewSet()
+  small.Add(IntKey(2))
+  small.Add(IntKey(3))
+  large := NewSet()
+  large.Add(IntKey(1))
+  large.Add(IntKey(2))
+  large.Add(IntKey(3))
+  other := NewSet()
+  other.Add(IntKey(3))
+  other.Add(IntKey(4))
+
+  assert.True(t, empty.IsSubsetOf(small))
+  assert.True(t, empty.IsSubsetOf(empty))
+  assert.False(t, small.IsSubsetOf(empty))
+  assert.True(t, small.IsSubsetOf(large))
+  assert.False(t, large.IsSubsetOf(small))
+  assert.False(t, other.IsSubsetOf(large))
+
+  assert.True(t, large.IsSupersetOf(small))
+  assert.True(t, small.IsSupersetOf(empty))
+  assert.False(t, small.IsSupersetOf(large))
+}
+
+func TestSetEqual(t *testing.T) {
+  a := NewSet()
+  b := NewSet()
+
+  assert.True(t, a.Equal(b))
+
+  a.Add(IntKey(1))
+  a.Add(IntKey(2))
+  b.Add(IntKey(2))
+
+  assert.False(t, a.Equal(b))
+  assert.False(t, b.Equal(a))
+
+  b.Add(IntKey(1))
+
+  assert.True(t, a.Equal(b))
+  assert.True(t, b.Equal(a))
+}
+
+func TestNewSetWithComparator(t *testing.T) {
+  set := NewSetWithComparator(ReverseComparator(DefaultComparator))
+  set.Add(IntKey(1))
+  set.Add(IntKey(3))
+  set.Add(IntKey(2))
+
+  assert.Equal(t, IntKey(3), set.Min())
+  assert.Equal(t, IntKey(1), set.Max())
+}
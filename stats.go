@@ -0,0 +1,56 @@
+package binarytree
+
+import(
+  "unsafe"
+)
+
+// TreeStats describes the shape of a tree, as returned by Tree.Stats.
+// Depths and heights count edges, so a tree holding only a root has height 0.
+type TreeStats struct {
+  // The number of nodes in the tree.
+  Count int
+  // The length of the longest path from the root to a leaf.
+  Height int
+  // The number of nodes without children.
+  Leaves int
+  MinLeafDepth int
+  MaxLeafDepth int
+  AvgLeafDepth float64
+  // The sum of the depths of every node.
+  InternalPathLength int
+  // The number of nodes with each balance factor, the height of a node's right subtree
+  // minus the height of its left subtree. An empty subtree has height -1.
+  BalanceHistogram map[int]int
+  // An estimate of the memory used by the tree's nodes. Memory referenced by keys and
+  // values is not included.
+  EstimatedBytes int64
+}
+
+// Walk the tree and return statistics describing its shape.
+func (me *Tree) Stats() TreeStats {
+  stats := TreeStats{ BalanceHistogram: map[int]int{} }
+  if me.root == nil { return stats }
+  leafDepths := 0
+  stats.MinLeafDepth = -1
+  var walk func(node *Node, depth int) int
+  walk = func(node *Node, depth int) int {
+    stats.Count++
+    stats.InternalPathLength += depth
+    if node.Left == nil && node.Right == nil {
+      stats.Leaves++
+      leafDepths += depth
+      if stats.MinLeafDepth < 0 || depth < stats.MinLeafDepth { stats.MinLeafDepth = depth }
+      if depth > stats.MaxLeafDepth { stats.MaxLeafDepth = depth }
+    }
+    left, right := -1, -1
+    if node.Left != nil { left = walk(node.Left, depth+1) }
+    if node.Right != nil { right = walk(node.Right, depth+1) }
+    stats.BalanceHistogram[right-left]++
+    if left > right { return left+1 }
+    return right+1
+  }
+  stats.Height = walk(me.root, 0)
+  stats.AvgLeafDepth = float64(leafDepths) / float64(stats.Leaves)
+  stats.EstimatedBytes = int64(stats.Count) * int64(unsafe.Sizeof(Node{}))
+  return stats
+}
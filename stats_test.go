@@ -0,0 +1,65 @@
+package binarytree
+
+import (
+  "testing"
+  "unsafe"
+  "github.com/stretchr/testify/assert"
+)
+
+func TestStatsEmpty(t *testing.T) {
+  stats := NewTree().Stats()
+
+  assert.Equal(t, 0, stats.Count)
+  assert.Equal(t, 0, stats.Height)
+  assert.Equal(t, 0, stats.Leaves)
+  assert.Equal(t, map[int]int{}, stats.BalanceHistogram)
+  assert.Equal(t, int64(0), stats.EstimatedBytes)
+}
+
+func TestStatsBalanced(t *testing.T) {
+  tree := NewTree()
+  tree.root = getTestTreeBalanced(1)
+
+  stats := tree.Stats()
+
+  assert.Equal(t, 7, stats.Count)
+  assert.Equal(t, 2, stats.Height)
+  assert.Equal(t, 4, stats.Leaves)
+  assert.Equal(t, 2, stats.MinLeafDepth)
+  assert.Equal(t, 2, stats.MaxLeafDepth)
+  assert.Equal(t, 2.0, stats.AvgLeafDepth)
+  assert.Equal(t, 10, stats.InternalPathLength)
+  assert.Equal(t, map[int]int{ 0: 7 }, stats.BalanceHistogram)
+  assert.Equal(t, int64(7) * int64(unsafe.Sizeof(Node{})), stats.EstimatedBytes)
+}
+
+func TestStatsUnbalanced(t *testing.T) {
+  tree := NewTree()
+  tree.root = getTestTreeRightUnbalanced(1)
+
+  stats := tree.Stats()
+
+  assert.Equal(t, 7, stats.Count)
+  assert.Equal(t, 6, stats.Height)
+  assert.Equal(t, 1, stats.Leaves)
+  assert.Equal(t, 6, stats.MinLeafDepth)
+  assert.Equal(t, 6, stats.MaxLeafDepth)
+  assert.Equal(t, 6.0, stats.AvgLeafDepth)
+  assert.Equal(t, 21, stats.InternalPathLength)
+  assert.Equal(t, map[int]int{ 0: 1, 1: 1, 2: 1, 3: 1, 4: 1, 5: 1, 6: 1 }, stats.BalanceHistogram)
+
+  // 4 with children 2 (leaf) and 6 -> 7
+  tree = NewTree()
+  for _, key := range []int{4, 2, 6, 7} { tree.Set(IntKey(key), key) }
+
+  stats = tree.Stats()
+
+  assert.Equal(t, 4, stats.Count)
+  assert.Equal(t, 2, stats.Height)
+  assert.Equal(t, 2, stats.Leaves)
+  assert.Equal(t, 1, stats.MinLeafDepth)
+  assert.Equal(t, 2, stats.MaxLeafDepth)
+  assert.Equal(t, 1.5, stats.AvgLeafDepth)
+  assert.Equal(t, 4, stats.InternalPathLength)
+  assert.Equal(t, map[int]int{ 0: 2, 1: 2 }, stats.BalanceHistogram)
+}
@@ -1,8 +1,26 @@
 package binarytree
 
+import(
+  "fmt"
+  "reflect"
+)
+
 // Tree represents a binary tree
+//
+// A Tree is not safe for concurrent use. Reads may run concurrently with each other, but not with
+// writes, so callers must guard the tree with a lock. Functions that take a sync.Locker for the tree,
+// such as StartReaper, NewLeader and Reconcile, must be given that lock.
 type Tree struct {
   root *Node
+  compare Comparator
+  watchers []*Subscription
+  version uint64
+  commits []txCommit
+  txs map[uint64]int
+  txCount int
+  txFloor uint64
+  ttl *ttlIndex
+  hasher *hasher
 }
 
 // Iterator is a func that can iterate a tree
@@ -13,18 +31,83 @@ func NewTree() *Tree {
   return &Tree{ root: nil }
 }
 
+// Return a new empty binary tree that orders its keys with the supplied Comparator
+// instead of the keys' own Comparable methods. The Comparator may order keys of different
+// types, so the key type checks of SetChecked, GetChecked, Apply and Validate are skipped.
+func NewTreeWithComparator(cmp Comparator) *Tree {
+  return &Tree{ root: nil, compare: cmp }
+}
+
+// Return the Comparator used to order this tree's keys.
+func (me *Tree) Comparator() Comparator {
+  if me.compare == nil { return DefaultComparator }
+  return me.compare
+}
+
 // Add the supplied key and value to the tree. If the key already exists, the value will be overwritten.
 func (me *Tree) Set(key Comparable, value interface{}) {
+  me.expire()
+  if me.ttl != nil { me.ttl.forget(key) }
+  var oldValue interface{}
   if me.root == nil {
     me.root = NewNodeKeyValue(key, value)
   } else {
-    node := me.root.Find(key)
+    node := me.root.find(key, me.Comparator())
     if node == nil {
-      me.root.Add(NewNodeKeyValue(key, value))
+      me.root.add(NewNodeKeyValue(key, value), me.Comparator())
     } else {
+      oldValue = node.Value
       node.Value = value
     }
   }
+  me.rehashPath(key, true)
+  me.emit(Event{ Op: EventSet, Key: key, OldValue: oldValue, NewValue: value })
+}
+
+// ErrKeyTypeMismatch is returned by the checked Tree methods when a key's type differs from
+// the type of the keys already in the tree.
+type ErrKeyTypeMismatch struct {
+  Expected reflect.Type
+  Actual reflect.Type
+}
+
+// Return the error message
+func (me *ErrKeyTypeMismatch) Error() string {
+  return fmt.Sprintf("binarytree: key type mismatch: tree holds %v keys, got %v", me.Expected, me.Actual)
+}
+
+// Return the type of the keys in the tree, as recorded by the first key inserted, or nil if the tree is empty.
+func (me *Tree) KeyType() reflect.Type {
+  if me.root == nil { return nil }
+  return reflect.TypeOf(me.root.Key)
+}
+
+// Return an *ErrKeyTypeMismatch if the supplied key's type differs from the tree's key type.
+// Trees with a caller-supplied Comparator accept keys of any type.
+func (me *Tree) checkKeyType(key Comparable) error {
+  if me.compare != nil { return nil }
+  expected := me.KeyType()
+  if expected == nil { return nil }
+  if actual := reflect.TypeOf(key); actual != expected {
+    return &ErrKeyTypeMismatch{ Expected: expected, Actual: actual }
+  }
+  return nil
+}
+
+// Add the supplied key and value to the tree as Set does, unless the key's type differs from the
+// type of the keys already in the tree, in which case return an *ErrKeyTypeMismatch.
+func (me *Tree) SetChecked(key Comparable, value interface{}) error {
+  if err := me.checkKeyType(key); err != nil { return err }
+  me.Set(key, value)
+  return nil
+}
+
+// Get the value associated with the supplied key as Get does, unless the key's type differs from the
+// type of the keys in the tree, in which case return an *ErrKeyTypeMismatch.
+func (me *Tree) GetChecked(key Comparable) (bool, interface{}, error) {
+  if err := me.checkKeyType(key); err != nil { return false, nil, err }
+  found, value := me.Get(key)
+  return found, value, nil
 }
 
 // Get the value associated with the supplied key. Return (true, value) if found,
@@ -39,38 +122,59 @@ func (me *Tree) Get(key Comparable) (bool, interface{}) {
 
 // Clear (Delete) the supplied key
 func (me *Tree) Clear(key Comparable) {
+  me.expire()
+  if me.ttl != nil { me.ttl.forget(key) }
   if me.root == nil { return }
-  me.root = me.root.Remove(key)
+  if len(me.watchers) > 0 {
+    node := me.root.find(key, me.Comparator())
+    if node == nil { return }
+    me.root = me.root.remove(key, me.Comparator())
+    me.rehashPath(key, false)
+    me.emit(Event{ Op: EventClear, Key: node.Key, OldValue: node.Value })
+    return
+  }
+  me.root = me.root.remove(key, me.Comparator())
+  me.rehashPath(key, false)
 }
 
 // Get the node associated with the supplied key, or nil if not found
 func (me *Tree) GetNode(key Comparable) *Node {
   if me.root == nil { return nil }
-  return me.root.Find(key)
+  node := me.root.find(key, me.Comparator())
+  if node == nil { return nil }
+  if expired := me.expired(); expired != nil && expired(node.Key) { return nil }
+  return node
 }
 
 // Return a deep copy of the tree.
 func (me *Tree) Copy() *Tree {
-  newTree := NewTree()
+  newTree := NewTreeWithComparator(me.compare)
   newTree.root = me.root
+  if me.ttl != nil { newTree.ttl = me.ttl.copy() }
+  newTree.hasher = me.hasher
   if me.root == nil {
     return newTree
   }
   newTree.root = me.root.Copy()
+  if me.hasher != nil { copyHashes(me.root, newTree.root) }
   return newTree
 }
 
 // Balance the tree.
 func (me *Tree) Balance() {
   if me.root == nil { return }
-  me.root = me.root.Balance()
+  me.root = me.root.balance(me.Comparator())
+  me.rehashAll(false)
 }
 
 // Return the value associated with the next smallest key than the supplied key.
 // If a smaller key exists, return (true, value), otherwise return (false, nil).
 func (me *Tree) Previous(key Comparable) (bool, Comparable, interface{}) {
   if me.root == nil { return false, nil, nil }
-  node := me.root.Previous(key)
+  node := me.root.previous(key, me.Comparator())
+  if expired := me.expired(); expired != nil {
+    for node != nil && expired(node.Key) { node = me.root.previous(node.Key, me.Comparator()) }
+  }
   if node == nil { return false, nil, nil }
   return true, node.Key, node.Value
 }
@@ -79,7 +183,10 @@ func (me *Tree) Previous(key Comparable) (bool, Comparable, interface{}) {
 // If a larger key exists, return (true, value), otherwise return (false, nil).
 func (me *Tree) Next(key Comparable) (bool, Comparable, interface{}) {
   if me.root == nil { return false, nil, nil }
-  node := me.root.Next(key)
+  node := me.root.next(key, me.Comparator())
+  if expired := me.expired(); expired != nil {
+    for node != nil && expired(node.Key) { node = me.root.next(node.Key, me.Comparator()) }
+  }
   if node == nil { return false, nil, nil }
   return true, node.Key, node.Value
 }
@@ -88,6 +195,11 @@ func (me *Tree) Next(key Comparable) (bool, Comparable, interface{}) {
 func (me *Tree) First() (Comparable, interface{}) {
    if me.root == nil { return nil, nil }
    node := me.root.Minimum()
+   if expired := me.expired(); expired != nil && expired(node.Key) {
+     found, key, value := me.Next(node.Key)
+     if !found { return nil, nil }
+     return key, value
+   }
    return node.Key, node.Value
 }
 
@@ -95,26 +207,87 @@ func (me *Tree) First() (Comparable, interface{}) {
 func (me *Tree) Last() (Comparable, interface{}) {
    if me.root == nil { return nil, nil }
    node := me.root.Maximum()
+   if expired := me.expired(); expired != nil && expired(node.Key) {
+     found, key, value := me.Previous(node.Key)
+     if !found { return nil, nil }
+     return key, value
+   }
    return node.Key, node.Value
 }
 
 // Iterate the tree with the function in the supplied direction
 func (me *Tree) Walk(iterator Iterator, forward bool) {
   if me.root == nil { return }
+  visit := me.unexpired(func(node *Node) { iterator(node.Key, node.Value) })
   if forward {
-    me.root.WalkForward(func(node *Node) { iterator(node.Key, node.Value)})
+    me.root.WalkForward(visit)
   } else {
-    me.root.WalkBackward(func(node *Node) { iterator(node.Key, node.Value)})
+    me.root.WalkBackward(visit)
   }
 }
 
 // Iterate the tree for all Nodes between the two keys, inclusive
 func (me *Tree) WalkRange(iterator func(key Comparable, value interface{}), from Comparable, to Comparable, forward bool) {
   if me.root == nil { return }
+  visit := me.unexpired(func(node *Node) { iterator(node.Key, node.Value) })
   if forward {
-    me.root.WalkRangeForward(func(node *Node) { iterator(node.Key, node.Value)}, from, to)
+    me.root.walkRangeForward(visit, from, to, me.Comparator())
   } else {
-    me.root.WalkRangeBackward(func(node *Node) { iterator(node.Key, node.Value)}, from, to)
+    me.root.walkRangeBackward(visit, from, to, me.Comparator())
   }
 }
 
+
+// Iterate the tree for all Nodes with keys beginning with the supplied prefix, in the supplied direction.
+// The walk seeks to the prefix and stops once keys no longer share it. The tree's keys must implement
+// Prefixable, and its Comparator must keep keys sharing a prefix together, as the default ordering of
+// StringKey and LexicalByteSliceKey does.
+func (me *Tree) WalkPrefix(iterator Iterator, prefix Prefixable, forward bool) {
+  if me.root == nil { return }
+  visit := me.unexpired(func(node *Node) { iterator(node.Key, node.Value) })
+  me.root.walkPrefix(func(node *Node) bool { visit(node); return true }, prefix, me.Comparator(), forward)
+}
+
+// Clear (Delete) all keys beginning with the supplied prefix, returning the number of keys removed.
+func (me *Tree) DeletePrefix(prefix Prefixable) int {
+  me.expire()
+  if me.root == nil { return 0 }
+  keys := []Comparable{}
+  me.root.walkPrefix(func(node *Node) bool { keys = append(keys, node.Key); return true }, prefix, me.Comparator(), true)
+  for _, key := range keys { me.Clear(key) }
+  return len(keys)
+}
+
+// keyBound is one end of a range of keys. A nil key is unbounded.
+type keyBound struct {
+  key Comparable
+  inclusive bool
+}
+
+// Return true if key is on the upper side of the lower bound.
+func (me keyBound) admitsAbove(key Comparable, cmp Comparator) bool {
+  if me.key == nil { return true }
+  c := cmp(key, me.key)
+  return c > 0 || (c == 0 && me.inclusive)
+}
+
+// Return true if key is on the lower side of the upper bound.
+func (me keyBound) admitsBelow(key Comparable, cmp Comparator) bool {
+  if me.key == nil { return true }
+  c := cmp(key, me.key)
+  return c < 0 || (c == 0 && me.inclusive)
+}
+
+// Call iterator for each node between the bounds whose key has not expired, in order.
+func (me *Tree) walkBounded(iterator func(node *Node), lower keyBound, upper keyBound) {
+  cmp := me.Comparator()
+  iterator = me.unexpired(iterator)
+  var walk func(node *Node)
+  walk = func(node *Node) {
+    above, below := lower.admitsAbove(node.Key, cmp), upper.admitsBelow(node.Key, cmp)
+    if node.Left != nil && above { walk(node.Left) }
+    if above && below { iterator(node) }
+    if node.Right != nil && below { walk(node.Right) }
+  }
+  if me.root != nil { walk(me.root) }
+}
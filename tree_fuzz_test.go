@@ -0,0 +1,114 @@
+package binarytree
+
+import (
+  "sort"
+  "testing"
+  "github.com/stretchr/testify/assert"
+)
+
+// treeModel is a reference implementation of Tree using a map and a sorted slice of keys.
+type treeModel struct {
+  values map[int]int
+}
+
+func (me *treeModel) keys() []int {
+  keys := []int{}
+  for key := range me.values { keys = append(keys, key) }
+  sort.Ints(keys)
+  return keys
+}
+
+// Fuzz keys fall in this range, so that operations often hit existing keys. Probes
+// run one past each end.
+const fuzzKeyRange = 32
+
+// FuzzTreeOps decodes data as a sequence of three byte operations, applies each one to
+// both a Tree and a treeModel, and checks after every step that they agree.
+func FuzzTreeOps(f *testing.F) {
+  f.Add([]byte{})
+  f.Add([]byte{ 0, 5, 1, 0, 3, 2, 0, 8, 3, 2, 5, 0 })
+  f.Add([]byte{ 0, 1, 1, 0, 2, 2, 0, 3, 3, 0, 4, 4, 0, 5, 5, 3, 0, 0, 2, 3, 0 })
+  f.Add([]byte{ 0, 9, 1, 0, 8, 2, 0, 7, 3, 0, 6, 4, 2, 9, 0, 2, 7, 0, 19, 0, 0 })
+
+  f.Fuzz(func(t *testing.T, data []byte) {
+    tree := NewTree()
+    model := &treeModel{ values: map[int]int{} }
+    for i:=0; i+2<len(data); i+=3 {
+      key, value := int(data[i+1]) % fuzzKeyRange, int(data[i+2])
+      switch data[i] % 4 {
+        case 0, 1:
+          tree.Set(IntKey(key), value)
+          model.values[key] = value
+        case 2:
+          tree.Clear(IntKey(key))
+          delete(model.values, key)
+        case 3:
+          tree.Balance()
+      }
+      if !checkTreeAgainstModel(t, tree, model, key, value % fuzzKeyRange) {
+        t.Fatalf("tree and model differ after op %d (%d, %d, %d):\n%s", i/3, data[i], data[i+1], data[i+2], tree)
+      }
+    }
+  })
+}
+
+func checkTreeAgainstModel(t *testing.T, tree *Tree, model *treeModel, from int, to int) bool {
+  keys := model.keys()
+  ok := assert.NoError(t, tree.Validate())
+
+  // Get
+  for key:=-1; key<=fuzzKeyRange; key++ {
+    found, value := tree.Get(IntKey(key))
+    expected, exists := model.values[key]
+    ok = assert.Equal(t, exists, found, "Get(%d)", key) && ok
+    if exists { ok = assert.Equal(t, expected, value, "Get(%d)", key) && ok }
+  }
+
+  // Next and Previous
+  for key:=-1; key<=fuzzKeyRange; key++ {
+    i := sort.SearchInts(keys, key+1)
+    found, next, _ := tree.Next(IntKey(key))
+    ok = assert.Equal(t, i < len(keys), found, "Next(%d)", key) && ok
+    if found && i < len(keys) { ok = assert.Equal(t, IntKey(keys[i]), next, "Next(%d)", key) && ok }
+
+    i = sort.SearchInts(keys, key) - 1
+    found, previous, _ := tree.Previous(IntKey(key))
+    ok = assert.Equal(t, i >= 0, found, "Previous(%d)", key) && ok
+    if found && i >= 0 { ok = assert.Equal(t, IntKey(keys[i]), previous, "Previous(%d)", key) && ok }
+  }
+
+  // First and Last
+  first, _ := tree.First()
+  last, _ := tree.Last()
+  if len(keys) == 0 {
+    ok = assert.Nil(t, first) && ok
+    ok = assert.Nil(t, last) && ok
+  } else {
+    ok = assert.Equal(t, IntKey(keys[0]), first) && ok
+    ok = assert.Equal(t, IntKey(keys[len(keys)-1]), last) && ok
+  }
+
+  // Walk
+  forward, backward := []int{}, []int{}
+  tree.Walk(func(key Comparable, value interface{}) { forward = append(forward, int(key.(IntKey))) }, true)
+  tree.Walk(func(key Comparable, value interface{}) { backward = append(backward, int(key.(IntKey))) }, false)
+  reversed := []int{}
+  for i:=len(keys)-1; i>=0; i-- { reversed = append(reversed, keys[i]) }
+  ok = assert.Equal(t, keys, forward, "Walk forward") && ok
+  ok = assert.Equal(t, reversed, backward, "Walk backward") && ok
+
+  // WalkRange
+  inRange := []int{}
+  for _, key := range keys {
+    if key >= from && key <= to { inRange = append(inRange, key) }
+  }
+  reversed = []int{}
+  for i:=len(inRange)-1; i>=0; i-- { reversed = append(reversed, inRange[i]) }
+  forward, backward = []int{}, []int{}
+  tree.WalkRange(func(key Comparable, value interface{}) { forward = append(forward, int(key.(IntKey))) }, IntKey(from), IntKey(to), true)
+  tree.WalkRange(func(key Comparable, value interface{}) { backward = append(backward, int(key.(IntKey))) }, IntKey(from), IntKey(to), false)
+  ok = assert.Equal(t, inRange, forward, "WalkRange(%d, %d) forward", from, to) && ok
+  ok = assert.Equal(t, reversed, backward, "WalkRange(%d, %d) backward", from, to) && ok
+
+  return ok
+}
@@ -1,6 +1,9 @@
 package binarytree
 
 import (
+  "errors"
+  "reflect"
+  "strings"
   "testing"
   "github.com/stretchr/testify/assert"
 )
@@ -397,4 +400,240 @@ func TestWalkRangeBackwardScale(t *testing.T) {
   }, IntKey(5), IntKey(10), false)
 
   assert.Equal(t, []int{9,6}, outkeys)
-}
\ No newline at end of file
+}
+
+func TestNewTreeWithComparator(t *testing.T) {
+  tree := NewTreeWithComparator(ReverseComparator(DefaultComparator))
+
+  assert.Nil(t, tree.root)
+  assert.Equal(t, 1, tree.Comparator()(IntKey(1), IntKey(2)))
+
+  // Zero value Tree uses the keys' own ordering
+  assert.Equal(t, -1, (&Tree{}).Comparator()(IntKey(1), IntKey(2)))
+}
+
+func TestTreeComparatorReverse(t *testing.T) {
+  tree := NewTreeWithComparator(ReverseComparator(DefaultComparator))
+  for i:=1; i<=7; i++ { tree.Set(IntKey(i), i) }
+
+  key, _ := tree.First()
+  assert.Equal(t, IntKey(7), key)
+  key, _ = tree.Last()
+  assert.Equal(t, IntKey(1), key)
+
+  found, key, _ := tree.Next(IntKey(4))
+  assert.True(t, found)
+  assert.Equal(t, IntKey(3), key)
+
+  found, key, _ = tree.Previous(IntKey(4))
+  assert.True(t, found)
+  assert.Equal(t, IntKey(5), key)
+
+  outkeys := []int{}
+  tree.WalkRange(func(key Comparable, value interface{}) {
+    outkeys = append(outkeys, key.ValueOf().(int))
+  }, IntKey(6), IntKey(2), true)
+  assert.Equal(t, []int{6,5,4,3,2}, outkeys)
+
+  tree.Clear(IntKey(4))
+  tree.Balance()
+  found, _ = tree.Get(IntKey(4))
+  assert.False(t, found)
+
+  outkeys = []int{}
+  tree.Walk(func(key Comparable, value interface{}) {
+    outkeys = append(outkeys, key.ValueOf().(int))
+  }, true)
+  assert.Equal(t, []int{7,6,5,3,2,1}, outkeys)
+
+  tree2 := tree.Copy()
+  tree2.Set(IntKey(10), 10)
+  key, _ = tree2.First()
+  assert.Equal(t, IntKey(10), key)
+}
+
+func TestTreeComparatorCaseInsensitive(t *testing.T) {
+  tree := NewTreeWithComparator(func(a, b Comparable) int {
+    return strings.Compare(strings.ToLower(a.ValueOf().(string)), strings.ToLower(b.ValueOf().(string)))
+  })
+
+  tree.Set(StringKey("Banana"), 1)
+  tree.Set(StringKey("apple"), 2)
+  tree.Set(StringKey("BANANA"), 3)
+
+  found, value := tree.Get(StringKey("banana"))
+  assert.True(t, found)
+  assert.Equal(t, 3, value)
+
+  key, _ := tree.First()
+  assert.Equal(t, StringKey("apple"), key)
+}
+
+func TestWalkPrefix(t *testing.T) {
+  tree := NewTree()
+
+  // Don't call if tree empty
+  tree.WalkPrefix(func(key Comparable, value interface{}) {
+    assert.Equal(t,1,2)
+  }, LexicalByteSliceKey("a"), true)
+
+  for _, key := range []string{"b", "aa", "ab", "abc", "a", "ac", "ba", "", "abd", "aba"} {
+    tree.Set(LexicalByteSliceKey(key), key)
+  }
+
+  outkeys := []string{}
+  tree.WalkPrefix(func(key Comparable, value interface{}) {
+    outkeys = append(outkeys, string(key.(LexicalByteSliceKey)))
+  }, LexicalByteSliceKey("ab"), true)
+  assert.Equal(t, []string{"ab","aba","abc","abd"}, outkeys)
+
+  outkeys = []string{}
+  tree.WalkPrefix(func(key Comparable, value interface{}) {
+    outkeys = append(outkeys, string(key.(LexicalByteSliceKey)))
+  }, LexicalByteSliceKey("a"), false)
+  assert.Equal(t, []string{"ac","abd","abc","aba","ab","aa","a"}, outkeys)
+
+  outkeys = []string{}
+  tree.WalkPrefix(func(key Comparable, value interface{}) {
+    outkeys = append(outkeys, string(key.(LexicalByteSliceKey)))
+  }, LexicalByteSliceKey("z"), true)
+  assert.Equal(t, []string{}, outkeys)
+
+  // StringKey is also Prefixable
+  tree = NewTree()
+  tree.Set(StringKey("user/1"), 1)
+  tree.Set(StringKey("user/2"), 2)
+  tree.Set(StringKey("group/1"), 3)
+
+  outkeys = []string{}
+  tree.WalkPrefix(func(key Comparable, value interface{}) {
+    outkeys = append(outkeys, key.ValueOf().(string))
+  }, StringKey("user/"), true)
+  assert.Equal(t, []string{"user/1","user/2"}, outkeys)
+}
+
+func TestDeletePrefix(t *testing.T) {
+  tree := NewTree()
+
+  assert.Equal(t, 0, tree.DeletePrefix(LexicalByteSliceKey("a")))
+
+  for _, key := range []string{"b", "aa", "ab", "abc", "a", "ac", "ba", "abd"} {
+    tree.Set(LexicalByteSliceKey(key), key)
+  }
+
+  assert.Equal(t, 3, tree.DeletePrefix(LexicalByteSliceKey("ab")))
+
+  outkeys := []string{}
+  tree.Walk(func(key Comparable, value interface{}) {
+    outkeys = append(outkeys, string(key.(LexicalByteSliceKey)))
+  }, true)
+  assert.Equal(t, []string{"a","aa","ac","b","ba"}, outkeys)
+}
+
+func TestKeyType(t *testing.T) {
+  tree := NewTree()
+
+  assert.Nil(t, tree.KeyType())
+
+  tree.Set(StringKey("one"), 1)
+  assert.Equal(t, reflect.TypeOf(StringKey("")), tree.KeyType())
+
+  tree.Clear(StringKey("one"))
+  assert.Nil(t, tree.KeyType())
+}
+
+func TestSetChecked(t *testing.T) {
+  tree := NewTree()
+
+  assert.NoError(t, tree.SetChecked(StringKey("one"), 1))
+  assert.NoError(t, tree.SetChecked(StringKey("two"), 2))
+
+  err := tree.SetChecked(IntKey(3), 3)
+  assert.Error(t, err)
+
+  var mismatch *ErrKeyTypeMismatch
+  assert.True(t, errors.As(err, &mismatch))
+  assert.Equal(t, reflect.TypeOf(StringKey("")), mismatch.Expected)
+  assert.Equal(t, reflect.TypeOf(IntKey(0)), mismatch.Actual)
+  assert.Equal(t, "binarytree: key type mismatch: tree holds binarytree.StringKey keys, got binarytree.IntKey", err.Error())
+
+  outkeys := []string{}
+  tree.Walk(func(key Comparable, value interface{}) {
+    outkeys = append(outkeys, key.ValueOf().(string))
+  }, true)
+  assert.Equal(t, []string{"one","two"}, outkeys)
+}
+
+func TestGetChecked(t *testing.T) {
+  tree := NewTree()
+
+  found, value, err := tree.GetChecked(IntKey(1))
+  assert.False(t, found)
+  assert.Nil(t, value)
+  assert.NoError(t, err)
+
+  tree.Set(StringKey("one"), 1)
+
+  found, value, err = tree.GetChecked(StringKey("one"))
+  assert.True(t, found)
+  assert.Equal(t, 1, value)
+  assert.NoError(t, err)
+
+  found, value, err = tree.GetChecked(IntKey(1))
+  assert.False(t, found)
+  assert.Nil(t, value)
+  var mismatch *ErrKeyTypeMismatch
+  assert.True(t, errors.As(err, &mismatch))
+}
+
+func TestMismatchedKeysDoNotPanic(t *testing.T) {
+  tree := NewTree()
+  tree.Set(StringKey("one"), 1)
+  tree.Set(StringKey("two"), 2)
+
+  assert.NotPanics(t, func() {
+    tree.Get(IntKey(1))
+    tree.Next(IntKey(1))
+    tree.Previous(IntKey(1))
+    tree.Clear(IntKey(1))
+    tree.Set(IntKey(1), 1)
+  })
+
+  // Mismatched keys never compare equal to existing keys
+  found, value := tree.Get(StringKey("one"))
+  assert.True(t, found)
+  assert.Equal(t, 1, value)
+
+  // The mismatched key is reported by Validate
+  var validationError *ValidationError
+  assert.True(t, errors.As(tree.Validate(), &validationError))
+  kinds := []ProblemKind{}
+  for _, problem := range validationError.Problems { kinds = append(kinds, problem.Kind) }
+  assert.Contains(t, kinds, ProblemKeyType)
+}
+
+func TestMixedKeysWithComparator(t *testing.T) {
+  // Order IntKey and Int64Key keys by their numeric value
+  numeric := func(a, b Comparable) int {
+    x, y := reflect.ValueOf(a).Int(), reflect.ValueOf(b).Int()
+    switch {
+      case x < y: return -1
+      case x > y: return 1
+    }
+    return 0
+  }
+  tree := NewTreeWithComparator(numeric)
+  tree.Set(IntKey(2), "two")
+  tree.Set(Int64Key(1), "one")
+  assert.NoError(t, tree.SetChecked(Int64Key(3), "three"))
+  assert.Nil(t, tree.Apply(NewBatch().Set(IntKey(4), "four").Set(Int64Key(5), "five")))
+  found, value, err := tree.GetChecked(Int64Key(2))
+  assert.NoError(t, err)
+  assert.True(t, found)
+  assert.Equal(t, "two", value)
+  assert.NoError(t, tree.Validate())
+
+  keys := []Comparable{}
+  tree.Walk(func(key Comparable, value interface{}) { keys = append(keys, key) }, true)
+  assert.Equal(t, []Comparable{ Int64Key(1), IntKey(2), Int64Key(3), IntKey(4), Int64Key(5) }, keys)
+}
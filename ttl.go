@@ -0,0 +1,232 @@
+package binarytree
+
+import(
+  "container/heap"
+  "sync"
+  "time"
+)
+
+// Clock is a func that returns the current time. Trees use time.Now unless another Clock is set with SetClock.
+type Clock func() time.Time
+
+// ExpireCallback is a func called with the key and value of each entry removed because its TTL expired
+type ExpireCallback func(key Comparable, value interface{})
+
+// ttlIndex tracks the expiry times of keys set with SetWithTTL. byKey maps each key to its
+// ttlEntry, and byExpiry is a min-heap of the same entries ordered by expiry time.
+type ttlIndex struct {
+  byKey *Tree
+  byExpiry expiryHeap
+  seq uint64
+  clock Clock
+  onExpire ExpireCallback
+}
+
+// ttlEntry is the expiry of a key. index is its position in the heap, so it can be removed without a search.
+type ttlEntry struct {
+  key Comparable
+  at time.Time
+  seq uint64
+  index int
+}
+
+// expiryHeap orders entries by time, then by the order they were set. It implements heap.Interface.
+type expiryHeap []*ttlEntry
+
+func (me expiryHeap) Len() int { return len(me) }
+
+func (me expiryHeap) Less(i, j int) bool {
+  if !me[i].at.Equal(me[j].at) { return me[i].at.Before(me[j].at) }
+  return me[i].seq < me[j].seq
+}
+
+func (me expiryHeap) Swap(i, j int) {
+  me[i], me[j] = me[j], me[i]
+  me[i].index, me[j].index = i, j
+}
+
+func (me *expiryHeap) Push(x interface{}) {
+  entry := x.(*ttlEntry)
+  entry.index = len(*me)
+  *me = append(*me, entry)
+}
+
+func (me *expiryHeap) Pop() interface{} {
+  old := *me
+  entry := old[len(old)-1]
+  old[len(old)-1] = nil
+  *me = old[:len(old)-1]
+  return entry
+}
+
+// Return the tree's TTL index, creating it if necessary.
+func (me *Tree) ttlIndex() *ttlIndex {
+  if me.ttl == nil {
+    me.ttl = &ttlIndex{ byKey: NewTreeWithComparator(me.Comparator()) }
+  }
+  return me.ttl
+}
+
+// Return the current time from the index's clock.
+func (me *ttlIndex) now() time.Time {
+  if me.clock == nil { return time.Now() }
+  return me.clock()
+}
+
+// Return the expiry entry of the supplied key, or nil if it has none.
+func (me *ttlIndex) entry(key Comparable) *ttlEntry {
+  if me.byKey.root == nil { return nil }
+  node := me.byKey.root.find(key, me.byKey.Comparator())
+  if node == nil { return nil }
+  return node.Value.(*ttlEntry)
+}
+
+// Stop tracking the expiry of the supplied key, if it has one.
+func (me *ttlIndex) forget(key Comparable) {
+  entry := me.entry(key)
+  if entry == nil { return }
+  me.byKey.Clear(key)
+  heap.Remove(&me.byExpiry, entry.index)
+}
+
+// Return a copy of the index for a copy of its tree.
+func (me *ttlIndex) copy() *ttlIndex {
+  index := &ttlIndex{ byKey: me.byKey.Copy(), byExpiry: make(expiryHeap, len(me.byExpiry)), seq: me.seq, clock: me.clock, onExpire: me.onExpire }
+  for i, entry := range me.byExpiry {
+    copied := *entry
+    index.byExpiry[i] = &copied
+  }
+  // The copied heap has the same layout, so each entry's index finds its copy
+  if index.byKey.root != nil {
+    index.byKey.root.walk(func(node *Node) bool {
+      node.Value = index.byExpiry[node.Value.(*ttlEntry).index]
+      return true
+    }, true)
+  }
+  return index
+}
+
+// Return the entries that have expired but not yet been removed, visiting only those heap entries.
+func (me *ttlIndex) expiredEntries() []*ttlEntry {
+  now := me.now()
+  entries := []*ttlEntry{}
+  var visit func(i int)
+  visit = func(i int) {
+    if i >= len(me.byExpiry) || me.byExpiry[i].at.After(now) { return }
+    entries = append(entries, me.byExpiry[i])
+    visit(2*i+1)
+    visit(2*i+2)
+  }
+  visit(0)
+  return entries
+}
+
+// Return a func reporting whether a key has expired but not yet been removed, or nil if no key has,
+// so reads can skip expired entries without changing the tree.
+func (me *Tree) expired() func(key Comparable) bool {
+  if me.ttl == nil || len(me.ttl.byExpiry) == 0 { return nil }
+  now := me.ttl.now()
+  if me.ttl.byExpiry[0].at.After(now) { return nil }
+  return func(key Comparable) bool {
+    entry := me.ttl.entry(key)
+    return entry != nil && !entry.at.After(now)
+  }
+}
+
+// Set the Clock used to decide when entries expire.
+func (me *Tree) SetClock(clock Clock) {
+  me.ttlIndex().clock = clock
+}
+
+// Set a callback to be called with the key and value of each entry as it expires.
+func (me *Tree) OnExpire(callback ExpireCallback) {
+  me.ttlIndex().onExpire = callback
+}
+
+// Add the supplied key and value to the tree, to be removed once ttl has passed. If the key already exists,
+// the value and TTL will be overwritten. Setting a key again with Set or Apply removes its TTL.
+//
+// Expired entries are never returned by Get, Next, Previous, First, Last or the walks, which skip them
+// without changing the tree, so concurrent reads are safe. They are removed by the next write to the
+// tree, by Expire, or by a reaper started with StartReaper.
+func (me *Tree) SetWithTTL(key Comparable, value interface{}, ttl time.Duration) {
+  me.Set(key, value)
+  index := me.ttlIndex()
+  index.seq++
+  entry := &ttlEntry{ key: key, at: index.now().Add(ttl), seq: index.seq }
+  index.byKey.Set(key, entry)
+  heap.Push(&index.byExpiry, entry)
+}
+
+// Return the time at which the supplied key expires. Return (true, time) if the key has a TTL and has
+// not expired, (false, time.Time{}) otherwise.
+func (me *Tree) Expiry(key Comparable) (bool, time.Time) {
+  if me.ttl == nil { return false, time.Time{} }
+  entry := me.ttl.entry(key)
+  if entry == nil || !entry.at.After(me.ttl.now()) { return false, time.Time{} }
+  return true, entry.at
+}
+
+// Remove all expired entries, returning the number removed.
+func (me *Tree) Expire() int {
+  return me.expire()
+}
+
+// Remove expired entries in order of expiry, calling the OnExpire callback for each.
+func (me *Tree) expire() int {
+  if me.ttl == nil || len(me.ttl.byExpiry) == 0 { return 0 }
+  index := me.ttl
+  now := index.now()
+  count := 0
+  for len(index.byExpiry) > 0 && !index.byExpiry[0].at.After(now) {
+    key := heap.Pop(&index.byExpiry).(*ttlEntry).key
+    index.byKey.Clear(key)
+    if me.root == nil { continue }
+    removed := me.root.find(key, me.Comparator())
+    if removed == nil { continue }
+    me.root = me.root.remove(key, me.Comparator())
+    me.rehashPath(key, false)
+    me.emit(Event{ Op: EventClear, Key: removed.Key, OldValue: removed.Value })
+    if index.onExpire != nil { index.onExpire(removed.Key, removed.Value) }
+    count++
+  }
+  return count
+}
+
+// Start a goroutine that removes expired entries every interval, holding locker, the tree's lock,
+// while it does so. Return a func that stops the reaper and waits for it to finish.
+func (me *Tree) StartReaper(interval time.Duration, locker sync.Locker) (stop func()) {
+  ticker := time.NewTicker(interval)
+  done := make(chan struct{})
+  finished := make(chan struct{})
+  go func() {
+    defer close(finished)
+    for {
+      select {
+        case <-ticker.C:
+          locker.Lock()
+          me.expire()
+          locker.Unlock()
+        case <-done:
+          return
+      }
+    }
+  }()
+  var once sync.Once
+  return func() {
+    once.Do(func() {
+      ticker.Stop()
+      close(done)
+      <-finished
+    })
+  }
+}
+
+// Return visit wrapped to skip nodes whose keys have expired.
+func (me *Tree) unexpired(visit func(node *Node)) func(node *Node) {
+  expired := me.expired()
+  if expired == nil { return visit }
+  return func(node *Node) {
+    if !expired(node.Key) { visit(node) }
+  }
+}
@@ -0,0 +1,228 @@
+package binarytree
+
+import (
+  "sync"
+  "testing"
+  "time"
+  "github.com/stretchr/testify/assert"
+)
+
+// fakeClock is a Clock that only moves when advanced
+type fakeClock struct {
+  mutex sync.Mutex
+  now time.Time
+}
+
+func (me *fakeClock) Now() time.Time {
+  me.mutex.Lock()
+  defer me.mutex.Unlock()
+  return me.now
+}
+
+func (me *fakeClock) Advance(d time.Duration) {
+  me.mutex.Lock()
+  defer me.mutex.Unlock()
+  me.now = me.now.Add(d)
+}
+
+// Return a fakeClock set to a fixed time
+func newFakeClock() *fakeClock {
+  return &fakeClock{ now: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC) }
+}
+
+func TestSetWithTTL(t *testing.T) {
+  tree, clock := NewTree(), newFakeClock()
+  tree.SetClock(clock.Now)
+  tree.SetWithTTL(IntKey(1), "one", time.Second)
+  tree.SetWithTTL(IntKey(2), "two", 2*time.Second)
+  tree.Set(IntKey(3), "three")
+
+  found, at := tree.Expiry(IntKey(1))
+  assert.True(t, found)
+  assert.Equal(t, clock.Now().Add(time.Second), at)
+  found, _ = tree.Expiry(IntKey(3))
+  assert.False(t, found)
+
+  clock.Advance(time.Second)
+  found, _ = tree.Get(IntKey(1))
+  assert.False(t, found)
+  found, value := tree.Get(IntKey(2))
+  assert.True(t, found)
+  assert.Equal(t, "two", value)
+
+  clock.Advance(time.Hour)
+  found, _ = tree.Get(IntKey(2))
+  assert.False(t, found)
+  found, _ = tree.Get(IntKey(3))
+  assert.True(t, found)
+  found, _ = tree.Expiry(IntKey(2))
+  assert.False(t, found)
+
+  // Reads leave expired entries in place until they are expired
+  assert.Equal(t, 3, tree.Stats().Count)
+  assert.Equal(t, 2, tree.Expire())
+  assert.Equal(t, 1, tree.Stats().Count)
+  assert.Equal(t, 0, tree.ttl.byKey.Stats().Count)
+  assert.Equal(t, 0, len(tree.ttl.byExpiry))
+}
+
+func TestSetWithTTLOverwrite(t *testing.T) {
+  tree, clock := NewTree(), newFakeClock()
+  tree.SetClock(clock.Now)
+  tree.SetWithTTL(IntKey(1), "one", time.Second)
+  tree.SetWithTTL(IntKey(1), "ONE", time.Minute)
+  tree.SetWithTTL(IntKey(2), "two", time.Second)
+  tree.Set(IntKey(2), "TWO")
+  tree.SetWithTTL(IntKey(3), "three", time.Second)
+  tree.Apply(NewBatch().Set(IntKey(3), "THREE"))
+  tree.SetWithTTL(IntKey(4), "four", time.Second)
+  tree.Clear(IntKey(4))
+  tree.Set(IntKey(4), "FOUR")
+
+  clock.Advance(time.Second)
+  assert.Equal(t, 0, tree.Expire())
+  _, value := tree.Get(IntKey(1))
+  assert.Equal(t, "ONE", value)
+  _, value = tree.Get(IntKey(2))
+  assert.Equal(t, "TWO", value)
+  _, value = tree.Get(IntKey(3))
+  assert.Equal(t, "THREE", value)
+  _, value = tree.Get(IntKey(4))
+  assert.Equal(t, "FOUR", value)
+
+  clock.Advance(time.Minute)
+  assert.Equal(t, 1, tree.Expire())
+}
+
+func TestTTLLazyExpiry(t *testing.T) {
+  tree, clock := NewTree(), newFakeClock()
+  tree.SetClock(clock.Now)
+  for i:=1; i<=6; i++ { tree.SetWithTTL(IntKey(i), i, time.Duration(2-i%2)*time.Second) }
+  clock.Advance(time.Second)
+
+  // Odd keys have expired
+  found, key, _ := tree.Next(IntKey(1))
+  assert.True(t, found)
+  assert.Equal(t, IntKey(2), key)
+  found, key, _ = tree.Previous(IntKey(6))
+  assert.Equal(t, IntKey(4), key)
+  first, _ := tree.First()
+  assert.Equal(t, IntKey(2), first)
+  last, _ := tree.Last()
+  assert.Equal(t, IntKey(6), last)
+
+  keys := []Comparable{}
+  tree.WalkRange(func(key Comparable, value interface{}) { keys = append(keys, key) }, IntKey(1), IntKey(6), true)
+  assert.Equal(t, []Comparable{ IntKey(2), IntKey(4), IntKey(6) }, keys)
+
+  found, _ = tree.Get(IntKey(3))
+  assert.False(t, found)
+  assert.Equal(t, 6, tree.Stats().Count)
+
+  clock.Advance(time.Second)
+  keys = []Comparable{}
+  tree.Walk(func(key Comparable, value interface{}) { keys = append(keys, key) }, true)
+  assert.Equal(t, []Comparable{}, keys)
+  first, _ = tree.First()
+  assert.Nil(t, first)
+  last, _ = tree.Last()
+  assert.Nil(t, last)
+}
+
+func TestTTLConcurrentReads(t *testing.T) {
+  tree, clock := NewTree(), newFakeClock()
+  tree.SetClock(clock.Now)
+  for i:=0; i<100; i++ { tree.SetWithTTL(IntKey(i), i, time.Duration(1+i%2) * time.Second) }
+  clock.Advance(time.Second)
+  mutex := &sync.RWMutex{}
+  wg := &sync.WaitGroup{}
+  for i:=0; i<4; i++ {
+    wg.Add(1)
+    go func() {
+      defer wg.Done()
+      mutex.RLock()
+      defer mutex.RUnlock()
+      count := 0
+      tree.Walk(func(key Comparable, value interface{}) { count++ }, true)
+      assert.Equal(t, 50, count)
+      found, _ := tree.Get(IntKey(2))
+      assert.False(t, found)
+    }()
+  }
+  wg.Wait()
+  assert.Equal(t, 100, tree.Stats().Count)
+}
+
+func TestTTLExpiryOrder(t *testing.T) {
+  tree, clock := NewTree(), newFakeClock()
+  tree.SetClock(clock.Now)
+  // Increasing expiry times, overwritten and cleared out of order
+  for i:=0; i<10000; i++ { tree.SetWithTTL(IntKey(i), i, time.Duration(i) * time.Millisecond) }
+  for i:=0; i<10000; i+=3 { tree.SetWithTTL(IntKey(i), i, time.Hour) }
+  for i:=1; i<10000; i+=3 { tree.Clear(IntKey(i)) }
+  expired := []Comparable{}
+  tree.OnExpire(func(key Comparable, value interface{}) { expired = append(expired, key) })
+  clock.Advance(time.Minute)
+  assert.Equal(t, 3333, tree.Expire())
+  for i:=1; i<len(expired); i++ { assert.True(t, expired[i-1].(IntKey) < expired[i].(IntKey)) }
+  assert.Equal(t, 3334, len(tree.ttl.byExpiry))
+}
+
+func TestTTLOnExpire(t *testing.T) {
+  tree, clock := NewTree(), newFakeClock()
+  tree.SetClock(clock.Now)
+  expired := []Comparable{}
+  tree.OnExpire(func(key Comparable, value interface{}) { expired = append(expired, key) })
+  sub := tree.Watch(nil, nil, &WatchOptions{ Buffer: 10 })
+
+  tree.SetWithTTL(IntKey(1), "one", 3*time.Second)
+  tree.SetWithTTL(IntKey(2), "two", time.Second)
+  tree.SetWithTTL(IntKey(3), "three", 2*time.Second)
+  tree.SetWithTTL(IntKey(4), "four", time.Minute)
+  clock.Advance(3*time.Second)
+
+  // Entries expire in order of expiry
+  assert.Equal(t, 3, tree.Expire())
+  assert.Equal(t, []Comparable{ IntKey(2), IntKey(3), IntKey(1) }, expired)
+  for i:=0; i<4; i++ { <-sub.Events() }
+  assert.Equal(t, Event{ Op: EventClear, Key: IntKey(2), OldValue: "two" }, <-sub.Events())
+}
+
+func TestTTLCopy(t *testing.T) {
+  tree, clock := NewTree(), newFakeClock()
+  tree.SetClock(clock.Now)
+  tree.SetWithTTL(IntKey(1), "one", time.Second)
+  copied := tree.Copy()
+  copied.SetWithTTL(IntKey(2), "two", time.Second)
+
+  clock.Advance(time.Second)
+  found, _ := copied.Get(IntKey(1))
+  assert.False(t, found)
+  assert.Equal(t, 1, tree.ttl.byKey.Stats().Count)
+  assert.Equal(t, 1, tree.Expire())
+}
+
+func TestTTLReaper(t *testing.T) {
+  tree, clock := NewTree(), newFakeClock()
+  tree.SetClock(clock.Now)
+  mutex := &sync.Mutex{}
+  expired := 0
+  tree.OnExpire(func(key Comparable, value interface{}) { expired++ })
+  mutex.Lock()
+  for i:=0; i<10; i++ { tree.SetWithTTL(IntKey(i), i, time.Second) }
+  mutex.Unlock()
+
+  stop := tree.StartReaper(time.Millisecond, mutex)
+  clock.Advance(time.Second)
+  assert.Eventually(t, func() bool {
+    mutex.Lock()
+    defer mutex.Unlock()
+    return expired == 10
+  }, time.Second, time.Millisecond)
+  stop()
+  stop()
+
+  mutex.Lock()
+  defer mutex.Unlock()
+  assert.Nil(t, tree.root)
+}
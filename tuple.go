@@ -0,0 +1,238 @@
+package binarytree
+
+import(
+  "bytes"
+  "encoding/binary"
+  "errors"
+  "fmt"
+  "math"
+  "time"
+)
+
+// TupleKey is a composite key encoded into a memcomparable byte form, in the style of
+// FoundationDB's tuple layer. Comparing two encoded TupleKeys with bytes.Compare gives the
+// same result as comparing their elements in order, so a TupleKey can be used as a prefix to
+// find all keys beginning with the same elements.
+//
+// Supported element types are nil, []byte, string, int, int64, float64, bool and time.Time.
+// Wrap an element with Desc to sort it in descending order; this also reverses the order
+// between elements of different types.
+//
+// Floats are ordered by their IEEE 754 bit patterns, so -0 sorts before +0. All NaNs are
+// encoded as the same value, which sorts above +Inf. Times are stored as nanoseconds since
+// the Unix epoch and decode in UTC, so only times from 1677-09-21 00:12:43.145224192 UTC to
+// 2262-04-11 23:47:16.854775807 UTC can be encoded; EncodeTuple returns an error for others.
+type TupleKey []byte
+
+// Desc wraps a tuple element so that it is encoded in descending order.
+type Desc struct {
+  Value interface{}
+}
+
+// ErrInvalidTuple is returned when a TupleKey cannot be decoded.
+var ErrInvalidTuple = errors.New("binarytree: invalid tuple encoding")
+
+// The range of times that can be encoded as nanoseconds since the Unix epoch in an int64.
+var (
+  tupleTimeMin = time.Unix(0, math.MinInt64)
+  tupleTimeMax = time.Unix(0, math.MaxInt64)
+)
+
+// Type codes. The codes of descending elements are the bitwise inverse of these, and must not
+// collide with them.
+const (
+  tupleNil byte = 0x00
+  tupleBytes byte = 0x01
+  tupleString byte = 0x02
+  tupleInt byte = 0x15
+  tupleFloat byte = 0x21
+  tupleFalse byte = 0x26
+  tupleTrue byte = 0x27
+  tupleTime byte = 0x33
+)
+
+// Encode the supplied elements into a TupleKey. Return an error if an element has an unsupported type.
+func EncodeTuple(elements ...interface{}) (TupleKey, error) {
+  out := []byte{}
+  for i, element := range elements {
+    var err error
+    if desc, ok := element.(Desc); ok {
+      start := len(out)
+      out, err = appendTupleElement(out, desc.Value)
+      for j:=start; j<len(out); j++ { out[j] = ^out[j] }
+    } else {
+      out, err = appendTupleElement(out, element)
+    }
+    if err != nil { return nil, fmt.Errorf("binarytree: tuple element %d: %w", i, err) }
+  }
+  return TupleKey(out), nil
+}
+
+// Encode the supplied elements into a TupleKey, panicking if an element has an unsupported type.
+func MustEncodeTuple(elements ...interface{}) TupleKey {
+  key, err := EncodeTuple(elements...)
+  if err != nil { panic(err) }
+  return key
+}
+
+// Decode the supplied TupleKey into its elements. Descending elements are returned wrapped in Desc,
+// ints are returned as int64, and times are returned in UTC.
+func DecodeTuple(key TupleKey) ([]interface{}, error) {
+  elements := []interface{}{}
+  data := []byte(key)
+  for len(data) > 0 {
+    desc := false
+    code := data[0]
+    if !isTupleCode(code) {
+      if !isTupleCode(^code) { return nil, ErrInvalidTuple }
+      desc = true
+      code = ^code
+    }
+    element, n, err := decodeTupleElement(code, data[1:], desc)
+    if err != nil { return nil, err }
+    if desc {
+      elements = append(elements, Desc{ Value: element })
+    } else {
+      elements = append(elements, element)
+    }
+    data = data[1+n:]
+  }
+  return elements, nil
+}
+
+// Return true if this key is less than the supplied TupleKey.
+func (me TupleKey) LessThan(other Comparable) bool {
+  otherKey, ok := other.(TupleKey)
+  if !ok { return false }
+  return bytes.Compare(me, otherKey) < 0
+}
+
+// Return true if this key is equal to the supplied TupleKey.
+func (me TupleKey) EqualTo(other Comparable) bool {
+  otherKey, ok := other.(TupleKey)
+  if !ok { return false }
+  return bytes.Equal(me, otherKey)
+}
+
+// Return true if this key is greater than the supplied TupleKey.
+func (me TupleKey) GreaterThan(other Comparable) bool {
+  otherKey, ok := other.(TupleKey)
+  if !ok { return false }
+  return bytes.Compare(me, otherKey) > 0
+}
+
+// Return the encoded []byte value as an interface
+func (me TupleKey) ValueOf() interface{} {
+  return []byte(me)
+}
+
+// Return true if this key begins with all the elements of the supplied TupleKey.
+func (me TupleKey) HasPrefix(prefix Comparable) bool {
+  otherKey, ok := prefix.(TupleKey)
+  if !ok { return false }
+  return bytes.HasPrefix(me, otherKey)
+}
+
+// Return the decoded elements of this key.
+func (me TupleKey) Elements() ([]interface{}, error) {
+  return DecodeTuple(me)
+}
+
+func isTupleCode(code byte) bool {
+  switch code {
+    case tupleNil, tupleBytes, tupleString, tupleInt, tupleFloat, tupleFalse, tupleTrue, tupleTime: return true
+  }
+  return false
+}
+
+func appendTupleElement(out []byte, element interface{}) ([]byte, error) {
+  switch value := element.(type) {
+    case nil:
+      return append(out, tupleNil), nil
+    case []byte:
+      return appendTupleBytes(append(out, tupleBytes), value), nil
+    case string:
+      return appendTupleBytes(append(out, tupleString), []byte(value)), nil
+    case int:
+      return appendTupleUint64(append(out, tupleInt), uint64(value) ^ (1 << 63)), nil
+    case int64:
+      return appendTupleUint64(append(out, tupleInt), uint64(value) ^ (1 << 63)), nil
+    case float64:
+      return appendTupleUint64(append(out, tupleFloat), float64Ordered(value)), nil
+    case bool:
+      if value { return append(out, tupleTrue), nil }
+      return append(out, tupleFalse), nil
+    case time.Time:
+      if value.Before(tupleTimeMin) || value.After(tupleTimeMax) { return nil, fmt.Errorf("time %v is out of range", value) }
+      return appendTupleUint64(append(out, tupleTime), uint64(value.UnixNano()) ^ (1 << 63)), nil
+    case Desc:
+      return nil, errors.New("nested Desc is not supported")
+  }
+  return nil, fmt.Errorf("unsupported type %T", element)
+}
+
+// Byte strings are terminated with 0x00 0x01, and any 0x00 inside them is escaped as 0x00 0xFF,
+// so that no encoded string is a prefix of another and the encoding still sorts when inverted.
+func appendTupleBytes(out []byte, value []byte) []byte {
+  for _, b := range value {
+    out = append(out, b)
+    if b == 0x00 { out = append(out, 0xFF) }
+  }
+  return append(out, 0x00, 0x01)
+}
+
+func appendTupleUint64(out []byte, value uint64) []byte {
+  var buf [8]byte
+  binary.BigEndian.PutUint64(buf[:], value)
+  return append(out, buf[:]...)
+}
+
+// Decode one element with the supplied (uninverted) type code, returning the element and the
+// number of bytes consumed after the type code.
+func decodeTupleElement(code byte, data []byte, desc bool) (interface{}, int, error) {
+  at := func(i int) byte {
+    if desc { return ^data[i] }
+    return data[i]
+  }
+  switch code {
+    case tupleNil: return nil, 0, nil
+    case tupleFalse: return false, 0, nil
+    case tupleTrue: return true, 0, nil
+    case tupleBytes, tupleString:
+      value := []byte{}
+      for i:=0; i<len(data); i++ {
+        if at(i) != 0x00 {
+          value = append(value, at(i))
+          continue
+        }
+        if i+1 >= len(data) { return nil, 0, ErrInvalidTuple }
+        switch at(i+1) {
+          case 0xFF:
+            value = append(value, 0x00)
+            i++
+          case 0x01:
+            if code == tupleString { return string(value), i+2, nil }
+            return value, i+2, nil
+          default:
+            return nil, 0, ErrInvalidTuple
+        }
+      }
+      return nil, 0, ErrInvalidTuple
+    case tupleInt, tupleFloat, tupleTime:
+      if len(data) < 8 { return nil, 0, ErrInvalidTuple }
+      var buf [8]byte
+      for i:=0; i<8; i++ { buf[i] = at(i) }
+      bits := binary.BigEndian.Uint64(buf[:])
+      switch code {
+        case tupleInt: return int64(bits ^ (1 << 63)), 8, nil
+        case tupleTime: return time.Unix(0, int64(bits ^ (1 << 63))).UTC(), 8, nil
+      }
+      if bits & (1 << 63) != 0 {
+        bits ^= 1 << 63
+      } else {
+        bits = ^bits
+      }
+      return math.Float64frombits(bits), 8, nil
+  }
+  return nil, 0, ErrInvalidTuple
+}
@@ -0,0 +1,189 @@
+package binarytree
+
+import (
+  "math"
+  "testing"
+  "time"
+  "github.com/stretchr/testify/assert"
+)
+
+func TestEncodeTupleOrder(t *testing.T) {
+  ordered := [][]interface{}{
+    { nil },
+    { []byte{} },
+    { []byte{ 0x00 } },
+    { []byte{ 0x00, 0x00 } },
+    { []byte{ 0x01 } },
+    { "" },
+    { "a" },
+    { "a", nil },
+    { "a", "b" },
+    { "a\x00" },
+    { "ab" },
+    { "b" },
+    { math.MinInt64 },
+    { -2 },
+    { int64(-1) },
+    { 0 },
+    { 1 },
+    { math.MaxInt64 },
+    { math.Inf(-1) },
+    { -1.5 },
+    { math.Copysign(0, -1) },
+    { 0.0 },
+    { 1.5 },
+    { math.Inf(1) },
+    { math.NaN() },
+    { false },
+    { true },
+    { time.Unix(-10, 0) },
+    { time.Unix(0, 0) },
+    { time.Unix(10, 5) },
+  }
+
+  for i:=1; i<len(ordered); i++ {
+    a := MustEncodeTuple(ordered[i-1]...)
+    b := MustEncodeTuple(ordered[i]...)
+    assert.True(t, a.LessThan(b), "%v < %v", ordered[i-1], ordered[i])
+    assert.True(t, b.GreaterThan(a), "%v > %v", ordered[i], ordered[i-1])
+    assert.False(t, a.EqualTo(b))
+  }
+}
+
+func TestEncodeTupleDescending(t *testing.T) {
+  // The order of element types is reversed too
+  ordered := [][]interface{}{
+    { "tenant", Desc{ 2.5 } },
+    { "tenant", Desc{ -2.5 } },
+    { "tenant", Desc{ 10 } },
+    { "tenant", Desc{ -10 } },
+    { "tenant", Desc{ "b" } },
+    { "tenant", Desc{ "ab" } },
+    { "tenant", Desc{ "a\x00" } },
+    { "tenant", Desc{ "a" } },
+    { "tenant", Desc{ "" } },
+  }
+
+  for i:=1; i<len(ordered); i++ {
+    a := MustEncodeTuple(ordered[i-1]...)
+    b := MustEncodeTuple(ordered[i]...)
+    assert.True(t, a.LessThan(b), "%v < %v", ordered[i-1], ordered[i])
+  }
+
+  // Elements after a descending element still sort ascending
+  a := MustEncodeTuple(Desc{ "x" }, 1)
+  b := MustEncodeTuple(Desc{ "x" }, 2)
+  assert.True(t, a.LessThan(b))
+}
+
+func TestEncodeTupleErrors(t *testing.T) {
+  _, err := EncodeTuple("ok", struct{}{})
+  assert.Error(t, err)
+
+  _, err = EncodeTuple(Desc{ Desc{ 1 } })
+  assert.Error(t, err)
+
+  // Times outside the range of UnixNano are rejected
+  for _, value := range []time.Time{ time.Date(1600, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2300, 1, 1, 0, 0, 0, 0, time.UTC), {} } {
+    _, err = EncodeTuple(value)
+    assert.Error(t, err, value)
+  }
+  for _, value := range []time.Time{ tupleTimeMin, tupleTimeMax } {
+    decoded, err := DecodeTuple(MustEncodeTuple(value))
+    assert.NoError(t, err)
+    assert.Equal(t, []interface{}{ value.UTC() }, decoded)
+  }
+
+  assert.Panics(t, func() { MustEncodeTuple(uint8(1)) })
+}
+
+func TestDecodeTuple(t *testing.T) {
+  now := time.Unix(1700000000, 123456789).UTC()
+  elements := []interface{}{
+    nil,
+    []byte{ 0x00, 0x01, 0xFF },
+    "tenant\x00one",
+    int64(-42),
+    3.25,
+    true,
+    false,
+    now,
+    Desc{ "desc" },
+    Desc{ int64(7) },
+    Desc{ []byte{ 0x00 } },
+    Desc{ nil },
+    Desc{ true },
+    Desc{ -0.5 },
+    Desc{ now },
+  }
+
+  key, err := EncodeTuple(elements...)
+  assert.NoError(t, err)
+
+  decoded, err := DecodeTuple(key)
+  assert.NoError(t, err)
+  assert.Equal(t, elements, decoded)
+
+  decoded, err = key.Elements()
+  assert.NoError(t, err)
+  assert.Equal(t, elements, decoded)
+
+  // Re-encoding decoded elements gives the same key
+  assert.Equal(t, key, MustEncodeTuple(decoded...))
+
+  // Ints decode as int64
+  decoded, err = DecodeTuple(MustEncodeTuple(5))
+  assert.NoError(t, err)
+  assert.Equal(t, []interface{}{ int64(5) }, decoded)
+
+  decoded, err = DecodeTuple(TupleKey{})
+  assert.NoError(t, err)
+  assert.Equal(t, []interface{}{}, decoded)
+}
+
+func TestDecodeTupleInvalid(t *testing.T) {
+  invalid := []TupleKey{
+    { 0x99 },
+    { tupleString, 'a' },
+    { tupleString, 'a', 0x00 },
+    { tupleString, 'a', 0x00, 0x02 },
+    { tupleInt, 0x00, 0x01 },
+    { ^tupleFloat, 0x00 },
+  }
+  for _, key := range invalid {
+    _, err := DecodeTuple(key)
+    assert.Equal(t, ErrInvalidTuple, err, "%v", key)
+  }
+}
+
+func TestTupleKeyHasPrefix(t *testing.T) {
+  key := MustEncodeTuple("tenant", 10, "id")
+
+  assert.True(t, key.HasPrefix(MustEncodeTuple("tenant")))
+  assert.True(t, key.HasPrefix(MustEncodeTuple("tenant", 10)))
+  assert.False(t, key.HasPrefix(MustEncodeTuple("ten")))
+  assert.False(t, key.HasPrefix(MustEncodeTuple("tenant", 1)))
+}
+
+func TestTupleKeyTreePrefix(t *testing.T) {
+  tree := NewTree()
+
+  tree.Set(MustEncodeTuple("acme", 3, "c"), "acme-3")
+  tree.Set(MustEncodeTuple("acme", 1, "a"), "acme-1")
+  tree.Set(MustEncodeTuple("ac", 1, "x"), "ac-1")
+  tree.Set(MustEncodeTuple("acme", 2, "b"), "acme-2")
+  tree.Set(MustEncodeTuple("acmes", 1, "y"), "acmes-1")
+  tree.Set(MustEncodeTuple("zeta", 1, "z"), "zeta-1")
+
+  out := []string{}
+  tree.WalkPrefix(func(key Comparable, value interface{}) {
+    out = append(out, value.(string))
+  }, MustEncodeTuple("acme"), true)
+  assert.Equal(t, []string{"acme-1","acme-2","acme-3"}, out)
+
+  out = []string{}
+  tree.WalkRange(func(key Comparable, value interface{}) {
+    out = append(out, value.(string))
+  }, MustEncodeTuple("acme", 2), MustEncodeTuple("acme", 3), true)
+  assert.Equal(t, []string{"acme-2"}, out)
+}
@@ -0,0 +1,214 @@
+package binarytree
+
+import(
+  "errors"
+  "fmt"
+)
+
+// ErrConflict is returned by Tx.Commit when another transaction wrote one of the same keys and
+// committed after this transaction began. The transaction is aborted.
+var ErrConflict = errors.New("binarytree: transaction conflict")
+
+// The number of commit records kept for conflict detection. Once more are made, the oldest are
+// dropped, and transactions that began before them can no longer commit.
+const txCommitLimit = 1024
+
+// ErrTxClosed is returned by Tx.Commit when the transaction has already been committed or aborted.
+var ErrTxClosed = errors.New("binarytree: transaction closed")
+
+// Tx is a transaction on a Tree, as returned by Tree.Begin. Writes are buffered in the transaction
+// and are visible to its own reads, merged with the current contents of the tree, until Commit
+// applies them atomically. Reads of keys the transaction has not written see the tree as it is at
+// the time of the read, not as it was when the transaction began.
+//
+// Conflicts are detected optimistically at Commit: a transaction fails if another transaction
+// committed a write to any key it wrote after it began. Writes made directly to the tree with
+// Set or Clear are not tracked. Like Tree itself, transactions are not safe for concurrent use
+// without external locking.
+//
+// Every transaction should be committed or aborted. The tree keeps a record of each commit made while
+// another transaction is open, and an abandoned transaction keeps those records from being dropped until
+// a fixed number of them have been made. Past that, the oldest records are dropped, and a transaction
+// that began before them fails to commit with ErrConflict.
+type Tx struct {
+  tree *Tree
+  version uint64
+  writes *Tree
+  done bool
+}
+
+// txWrite is a buffered write in a transaction
+type txWrite struct {
+  value interface{}
+  clear bool
+}
+
+// txCommit records the keys written by a committed transaction, for conflict detection
+type txCommit struct {
+  version uint64
+  keys []Comparable
+}
+
+// Begin a new transaction on the tree.
+func (me *Tree) Begin() *Tx {
+  tx := &Tx{ tree: me, version: me.version, writes: NewTreeWithComparator(me.Comparator()) }
+  if me.txs == nil { me.txs = map[uint64]int{} }
+  me.txs[tx.version]++
+  me.txCount++
+  return tx
+}
+
+// Get the value associated with the supplied key, including the transaction's own writes.
+// Return (true, value) if found, (false, nil) if not.
+func (me *Tx) Get(key Comparable) (bool, interface{}) {
+  if found, write := me.writes.Get(key); found {
+    if write.(txWrite).clear { return false, nil }
+    return true, write.(txWrite).value
+  }
+  return me.tree.Get(key)
+}
+
+// Add the supplied key and value to the transaction. If the key already exists, the value will be overwritten on Commit.
+func (me *Tx) Set(key Comparable, value interface{}) {
+  me.writes.Set(key, txWrite{ value: value })
+}
+
+// Clear (Delete) the supplied key in the transaction.
+func (me *Tx) Clear(key Comparable) {
+  me.writes.Set(key, txWrite{ clear: true })
+}
+
+// Return the value associated with the next largest key than the supplied key, including the transaction's own writes.
+// If a larger key exists, return (true, key, value), otherwise return (false, nil, nil).
+func (me *Tx) Next(key Comparable) (bool, Comparable, interface{}) {
+  return me.step(key, true)
+}
+
+// Return the value associated with the next smallest key than the supplied key, including the transaction's own writes.
+// If a smaller key exists, return (true, key, value), otherwise return (false, nil, nil).
+func (me *Tx) Previous(key Comparable) (bool, Comparable, interface{}) {
+  return me.step(key, false)
+}
+
+func (me *Tx) step(key Comparable, forward bool) (bool, Comparable, interface{}) {
+  move := func(tree *Tree, key Comparable) (bool, Comparable, interface{}) {
+    if forward { return tree.Next(key) }
+    return tree.Previous(key)
+  }
+  // Keys the transaction has written are taken from the writes, not the tree
+  found, treeKey, treeValue := move(me.tree, key)
+  for found && me.writes.GetNode(treeKey) != nil { found, treeKey, treeValue = move(me.tree, treeKey) }
+  writeFound, writeKey, write := move(me.writes, key)
+  for writeFound && write.(txWrite).clear { writeFound, writeKey, write = move(me.writes, writeKey) }
+
+  if !writeFound { return found, treeKey, treeValue }
+  if !found { return true, writeKey, write.(txWrite).value }
+  c := me.tree.Comparator()(writeKey, treeKey)
+  if !forward { c = -c }
+  if c < 0 { return true, writeKey, write.(txWrite).value }
+  return true, treeKey, treeValue
+}
+
+// Iterate all keys between the two keys, inclusive, including the transaction's own writes.
+func (me *Tx) WalkRange(iterator Iterator, from Comparable, to Comparable, forward bool) {
+  keys, writes := []Comparable{}, []txWrite{}
+  me.writes.WalkRange(func(key Comparable, value interface{}) {
+    keys = append(keys, key)
+    writes = append(writes, value.(txWrite))
+  }, from, to, forward)
+
+  cmp := me.tree.Comparator()
+  i := 0
+  me.tree.WalkRange(func(key Comparable, value interface{}) {
+    for ; i<len(keys); i++ {
+      c := cmp(keys[i], key)
+      if !forward { c = -c }
+      if c > 0 { break }
+      if !writes[i].clear { iterator(keys[i], writes[i].value) }
+      if c == 0 { i++; return }
+    }
+    iterator(key, value)
+  }, from, to, forward)
+  for ; i<len(keys); i++ {
+    if !writes[i].clear { iterator(keys[i], writes[i].value) }
+  }
+}
+
+// Apply the transaction's writes to the tree atomically, as Tree.Apply does. Return an error wrapping
+// ErrConflict if another transaction committed a write to the same key after this one began, or
+// any error returned by Tree.Apply, in which case nothing is written. The transaction is closed
+// whether or not Commit succeeds.
+func (me *Tx) Commit() error {
+  if me.done { return ErrTxClosed }
+  defer me.close()
+  tree := me.tree
+  // A transaction that wrote nothing cannot conflict
+  if me.writes.root == nil { return nil }
+  if me.version < tree.txFloor { return fmt.Errorf("%w: transaction is older than the commits kept", ErrConflict) }
+  for _, commit := range tree.commits {
+    if commit.version <= me.version { continue }
+    for _, key := range commit.keys {
+      if me.writes.GetNode(key) != nil { return fmt.Errorf("%w on key %v", ErrConflict, key) }
+    }
+  }
+
+  batch := NewBatch()
+  keys := []Comparable{}
+  me.writes.Walk(func(key Comparable, value interface{}) {
+    write := value.(txWrite)
+    if write.clear {
+      batch.Clear(key)
+    } else {
+      batch.Set(key, write.value)
+    }
+    keys = append(keys, key)
+  }, true)
+  if err := tree.Apply(batch); err != nil { return err }
+
+  tree.version++
+  if tree.txCount > 1 { tree.record(txCommit{ version: tree.version, keys: keys }) }
+  return nil
+}
+
+// Discard the transaction's writes. Abort may be called more than once, and after Commit.
+func (me *Tx) Abort() {
+  if me.done { return }
+  me.close()
+}
+
+// Record a commit for conflict detection, dropping the oldest records over txCommitLimit along with
+// the transactions that needed them.
+func (me *Tree) record(commit txCommit) {
+  me.commits = append(me.commits, commit)
+  if len(me.commits) <= txCommitLimit { return }
+  dropped := len(me.commits) - txCommitLimit
+  me.txFloor = me.commits[dropped-1].version
+  me.commits = append([]txCommit{}, me.commits[dropped:]...)
+  for version, count := range me.txs {
+    if version >= me.txFloor { continue }
+    delete(me.txs, version)
+    me.txCount -= count
+  }
+}
+
+// Mark the transaction done and drop commit records no open transaction needs.
+func (me *Tx) close() {
+  me.done = true
+  tree := me.tree
+  // Transactions older than the records kept are no longer counted
+  if me.version < tree.txFloor { return }
+  tree.txs[me.version]--
+  if tree.txs[me.version] == 0 { delete(tree.txs, me.version) }
+  tree.txCount--
+  if tree.txCount == 0 {
+    tree.commits = nil
+    return
+  }
+  oldest := tree.version
+  for version := range tree.txs {
+    if version < oldest { oldest = version }
+  }
+  i := 0
+  for i < len(tree.commits) && tree.commits[i].version <= oldest { i++ }
+  tree.commits = tree.commits[i:]
+}
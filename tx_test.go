@@ -0,0 +1,206 @@
+package binarytree
+
+import (
+  "errors"
+  "testing"
+  "github.com/stretchr/testify/assert"
+)
+
+// The keys 1..7, in an order that builds a balanced tree
+var txTestKeys = []int{ 4, 2, 6, 1, 3, 5, 7 }
+
+func TestTxGetSetClear(t *testing.T) {
+  tree := getTestTreeWithKeys(txTestKeys)
+  tx := tree.Begin()
+  tx.Set(IntKey(8), "eight")
+  tx.Set(IntKey(2), "two")
+  tx.Clear(IntKey(3))
+
+  found, value := tx.Get(IntKey(8))
+  assert.True(t, found)
+  assert.Equal(t, "eight", value)
+  _, value = tx.Get(IntKey(2))
+  assert.Equal(t, "two", value)
+  found, _ = tx.Get(IntKey(3))
+  assert.False(t, found)
+  _, value = tx.Get(IntKey(4))
+  assert.Equal(t, 4, value)
+
+  // The tree is unchanged until Commit
+  found, _ = tree.Get(IntKey(8))
+  assert.False(t, found)
+  _, value = tree.Get(IntKey(2))
+  assert.Equal(t, 2, value)
+
+  assert.Nil(t, tx.Commit())
+  _, value = tree.Get(IntKey(8))
+  assert.Equal(t, "eight", value)
+  _, value = tree.Get(IntKey(2))
+  assert.Equal(t, "two", value)
+  found, _ = tree.Get(IntKey(3))
+  assert.False(t, found)
+
+  assert.Equal(t, ErrTxClosed, tx.Commit())
+}
+
+func TestTxNextPrevious(t *testing.T) {
+  tree := getTestTreeWithKeys(txTestKeys)
+  tx := tree.Begin()
+  tx.Clear(IntKey(2))
+  tx.Clear(IntKey(3))
+  tx.Set(IntKey(0), "zero")
+  tx.Set(IntKey(5), "five")
+  tx.Clear(IntKey(7))
+  tx.Clear(IntKey(9))
+
+  keys, values := []Comparable{}, []interface{}{}
+  for found, key, value := tx.Next(IntKey(-1)); found; found, key, value = tx.Next(key) {
+    keys = append(keys, key)
+    values = append(values, value)
+  }
+  assert.Equal(t, []Comparable{ IntKey(0), IntKey(1), IntKey(4), IntKey(5), IntKey(6) }, keys)
+  assert.Equal(t, []interface{}{ "zero", 1, 4, "five", 6 }, values)
+
+  keys = []Comparable{}
+  for found, key, _ := tx.Previous(IntKey(10)); found; found, key, _ = tx.Previous(key) { keys = append(keys, key) }
+  assert.Equal(t, []Comparable{ IntKey(6), IntKey(5), IntKey(4), IntKey(1), IntKey(0) }, keys)
+}
+
+func TestTxWalkRange(t *testing.T) {
+  tree := getTestTreeWithKeys(txTestKeys)
+  tx := tree.Begin()
+  tx.Set(IntKey(0), "zero")
+  tx.Clear(IntKey(2))
+  tx.Set(IntKey(3), "three")
+  tx.Set(IntKey(6), "six")
+  tx.Set(IntKey(9), "nine")
+
+  keys, values := []Comparable{}, []interface{}{}
+  tx.WalkRange(func(key Comparable, value interface{}) {
+    keys = append(keys, key)
+    values = append(values, value)
+  }, IntKey(0), IntKey(10), true)
+  assert.Equal(t, []Comparable{ IntKey(0), IntKey(1), IntKey(3), IntKey(4), IntKey(5), IntKey(6), IntKey(7), IntKey(9) }, keys)
+  assert.Equal(t, []interface{}{ "zero", 1, "three", 4, 5, "six", 7, "nine" }, values)
+
+  keys = []Comparable{}
+  tx.WalkRange(func(key Comparable, value interface{}) { keys = append(keys, key) }, IntKey(2), IntKey(6), false)
+  assert.Equal(t, []Comparable{ IntKey(6), IntKey(5), IntKey(4), IntKey(3) }, keys)
+
+  // Writes outside the tree's keys
+  keys = []Comparable{}
+  NewTree().Begin().WalkRange(func(key Comparable, value interface{}) { keys = append(keys, key) }, IntKey(0), IntKey(10), true)
+  assert.Equal(t, []Comparable{}, keys)
+}
+
+func TestTxAbort(t *testing.T) {
+  tree := getTestTreeWithKeys(txTestKeys)
+  tx := tree.Begin()
+  tx.Set(IntKey(1), "one")
+  tx.Clear(IntKey(2))
+  tx.Abort()
+  tx.Abort()
+
+  assert.Equal(t, ErrTxClosed, tx.Commit())
+  _, value := tree.Get(IntKey(1))
+  assert.Equal(t, 1, value)
+  found, _ := tree.Get(IntKey(2))
+  assert.True(t, found)
+  assert.Equal(t, 0, len(tree.txs))
+}
+
+func TestTxConflict(t *testing.T) {
+  tree := getTestTreeWithKeys(txTestKeys)
+  a := tree.Begin()
+  b := tree.Begin()
+  c := tree.Begin()
+  a.Set(IntKey(1), "a")
+  a.Set(IntKey(2), "a")
+  b.Clear(IntKey(2))
+  c.Set(IntKey(3), "c")
+
+  assert.Nil(t, a.Commit())
+  err := b.Commit()
+  assert.True(t, errors.Is(err, ErrConflict))
+  assert.Equal(t, "binarytree: transaction conflict on key 2", err.Error())
+  // Transactions that wrote other keys commit
+  assert.Nil(t, c.Commit())
+
+  _, value := tree.Get(IntKey(2))
+  assert.Equal(t, "a", value)
+  _, value = tree.Get(IntKey(3))
+  assert.Equal(t, "c", value)
+
+  // Transactions that began after the commit do not conflict with it
+  d := tree.Begin()
+  d.Set(IntKey(2), "d")
+  assert.Nil(t, d.Commit())
+  _, value = tree.Get(IntKey(2))
+  assert.Equal(t, "d", value)
+
+  // Commit records are dropped once no open transaction needs them
+  assert.Equal(t, 0, len(tree.txs))
+  assert.Equal(t, 0, len(tree.commits))
+}
+
+func TestTxCommitPruning(t *testing.T) {
+  tree := getTestTreeWithKeys(txTestKeys)
+  old := tree.Begin()
+  for i:=0; i<3; i++ {
+    tx := tree.Begin()
+    tx.Set(IntKey(10+i), i)
+    assert.Nil(t, tx.Commit())
+  }
+  assert.Equal(t, 3, len(tree.commits))
+
+  recent := tree.Begin()
+  old.Abort()
+  assert.Equal(t, 0, len(tree.commits))
+  recent.Set(IntKey(10), "recent")
+  assert.Nil(t, recent.Commit())
+}
+
+func TestTxAbandoned(t *testing.T) {
+  tree := getTestTreeWithKeys(txTestKeys)
+  abandoned := tree.Begin()
+  abandoned.Set(IntKey(1), "abandoned")
+  old := tree.Begin()
+  old.Set(IntKey(2), "old")
+  readOnly := tree.Begin()
+  for i:=0; i<txCommitLimit; i++ {
+    tx := tree.Begin()
+    tx.Set(IntKey(i), i)
+    assert.Nil(t, tx.Commit())
+  }
+  assert.Equal(t, txCommitLimit, len(tree.commits))
+
+  // Past the limit the oldest records are dropped, and with them the transactions that needed them
+  tx := tree.Begin()
+  tx.Set(IntKey(0), "dropped")
+  assert.Nil(t, tx.Commit())
+  assert.Equal(t, 0, tree.txCount)
+  assert.Equal(t, 0, len(tree.txs))
+  assert.Equal(t, 0, len(tree.commits))
+
+  // Transactions older than the records kept cannot commit
+  err := old.Commit()
+  assert.True(t, errors.Is(err, ErrConflict))
+  assert.Equal(t, "binarytree: transaction conflict: transaction is older than the commits kept", err.Error())
+  // A transaction that wrote nothing still commits
+  readOnly.Get(IntKey(1))
+  assert.Nil(t, readOnly.Commit())
+  abandoned.Abort()
+  assert.Equal(t, 0, tree.txCount)
+}
+
+func TestTxCommitInvalid(t *testing.T) {
+  tree := getTestTreeWithKeys(txTestKeys)
+  tx := tree.Begin()
+  tx.Set(IntKey(9), "nine")
+  tx.Set(StringKey("x"), "x")
+  var mismatch *ErrKeyTypeMismatch
+  assert.True(t, errors.As(tx.Commit(), &mismatch))
+  found, _ := tree.Get(IntKey(9))
+  assert.False(t, found)
+  assert.Equal(t, 0, len(tree.txs))
+}
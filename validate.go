@@ -0,0 +1,156 @@
+package binarytree
+
+import(
+  "bytes"
+  "fmt"
+  "reflect"
+  "strings"
+)
+
+// ProblemKind identifies a kind of structural problem found by Tree.Validate
+type ProblemKind int
+
+const (
+  // A key is out of order with respect to one of its ancestors.
+  ProblemOrder ProblemKind = iota
+  // A node is reachable more than once, i.e. the tree contains a cycle or a shared subtree.
+  ProblemCycle
+  // A node has a nil key.
+  ProblemNilKey
+  // A key is equal to the key of one of its ancestors.
+  ProblemDuplicateKey
+  // A node's hashes, maintained once Tree.EnableHashing has been called, do not match its contents.
+  ProblemHash
+  // A key's type differs from the type of the root's key.
+  ProblemKeyType
+)
+
+// Return the name of the problem kind
+func (me ProblemKind) String() string {
+  switch me {
+    case ProblemOrder: return "order violation"
+    case ProblemCycle: return "cycle"
+    case ProblemNilKey: return "nil key"
+    case ProblemDuplicateKey: return "duplicate key"
+    case ProblemHash: return "stale hash"
+    case ProblemKeyType: return "mixed key types"
+  }
+  return fmt.Sprintf("ProblemKind(%d)", int(me))
+}
+
+// Problem is a single structural problem found by Tree.Validate
+type Problem struct {
+  Kind ProblemKind
+  // The keys of the nodes from the root to the offending node, inclusive.
+  Path []Comparable
+  Message string
+}
+
+// Return a description of the problem and where it was found
+func (me Problem) String() string {
+  path := make([]string, len(me.Path))
+  for i, key := range me.Path { path[i] = fmt.Sprintf("%v", key) }
+  return fmt.Sprintf("%v at [%s]: %s", me.Kind, strings.Join(path, " "), me.Message)
+}
+
+// ValidationError is returned by Tree.Validate and lists every problem found
+type ValidationError struct {
+  Problems []Problem
+}
+
+// Return the error message
+func (me *ValidationError) Error() string {
+  problems := make([]string, len(me.Problems))
+  for i, problem := range me.Problems { problems[i] = problem.String() }
+  return fmt.Sprintf("binarytree: tree is invalid: %d problem(s): %s", len(me.Problems), strings.Join(problems, "; "))
+}
+
+// Walk the structure of the tree and check that every key is non-nil, unique and in order according
+// to the tree's Comparator, and of the same type unless the tree has a caller-supplied Comparator,
+// and that no node is reachable more than once. If hashing is enabled, also check that every node's
+// hashes match its entry and subtree.
+// Return nil if the tree is valid, otherwise a *ValidationError listing every problem found.
+func (me *Tree) Validate() error {
+  if me.root == nil { return nil }
+  v := &validator{ cmp: me.Comparator(), hasher: me.hasher, visited: map[*Node]bool{} }
+  if me.compare == nil { v.keyType = me.KeyType() }
+  v.validate(me.root, nil, nil, []Comparable{})
+  if len(v.problems) == 0 { return nil }
+  return &ValidationError{ Problems: v.problems }
+}
+
+type validator struct {
+  cmp Comparator
+  hasher *hasher
+  keyType reflect.Type
+  visited map[*Node]bool
+  problems []Problem
+}
+
+func (me *validator) report(kind ProblemKind, path []Comparable, format string, args ...interface{}) {
+  me.problems = append(me.problems, Problem{ Kind: kind, Path: append([]Comparable{}, path...), Message: fmt.Sprintf(format, args...) })
+}
+
+// Check the subtree at node, whose keys must lie strictly between the keys of the nodes lower
+// and upper, either of which may be nil for an open bound.
+func (me *validator) validate(node *Node, lower *Node, upper *Node, path []Comparable) {
+  path = append(path, node.Key)
+  if me.visited[node] {
+    me.report(ProblemCycle, path, "node is reachable more than once")
+    return
+  }
+  me.visited[node] = true
+
+  leftUpper, rightLower := upper, lower
+  if node.Key == nil {
+    me.report(ProblemNilKey, path, "node has a nil key")
+  } else {
+    if actual := reflect.TypeOf(node.Key); me.keyType != nil && actual != me.keyType {
+      me.report(ProblemKeyType, path, "key %v is a %v, the tree holds %v keys", node.Key, actual, me.keyType)
+    }
+    if lower != nil { me.checkBound(node, lower, 1, path) }
+    if upper != nil { me.checkBound(node, upper, -1, path) }
+    leftUpper, rightLower = node, node
+  }
+
+  if node.Left != nil { me.validate(node.Left, lower, leftUpper, path) }
+  if node.Right != nil { me.validate(node.Right, rightLower, upper, path) }
+  if me.hasher != nil { me.checkHash(node, path) }
+}
+
+// Check that node's hashes match its entry and the hashes of its children.
+func (me *validator) checkHash(node *Node, path []Comparable) {
+  if node.hash == nil {
+    me.report(ProblemHash, path, "node has no hash")
+    return
+  }
+  if !bytes.Equal(node.hash.entry, me.hasher.entry(node)) {
+    me.report(ProblemHash, path, "entry hash does not match key %v and value %v", node.Key, node.Value)
+  }
+  subtree := append([]byte{}, node.hash.entry...)
+  count := 1
+  for _, child := range []*Node{ node.Left, node.Right } {
+    if child == nil || child.hash == nil { continue }
+    xorHash(subtree, child.hash.subtree)
+    count += child.hash.count
+  }
+  if !bytes.Equal(node.hash.subtree, subtree) || node.hash.count != count {
+    me.report(ProblemHash, path, "subtree hash does not match its children")
+  }
+}
+
+// Check that node's key compares to the bound's key with the supplied sign.
+func (me *validator) checkBound(node *Node, bound *Node, sign int, path []Comparable) {
+  c := me.cmp(node.Key, bound.Key)
+  if c == 0 {
+    me.report(ProblemDuplicateKey, path, "key %v is equal to ancestor key %v", node.Key, bound.Key)
+    return
+  }
+  if (c < 0) != (sign < 0) {
+    if sign < 0 {
+      me.report(ProblemOrder, path, "key %v is not less than ancestor key %v", node.Key, bound.Key)
+    } else {
+      me.report(ProblemOrder, path, "key %v is not greater than ancestor key %v", node.Key, bound.Key)
+    }
+  }
+}
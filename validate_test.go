@@ -0,0 +1,131 @@
+package binarytree
+
+import (
+  "errors"
+  "testing"
+  "github.com/stretchr/testify/assert"
+)
+
+func TestValidate(t *testing.T) {
+  tree := NewTree()
+  assert.NoError(t, tree.Validate())
+
+  tree.root = getTestTreeBalanced(1)
+  assert.NoError(t, tree.Validate())
+
+  tree.root = getTestTreeLeftUnbalanced(1)
+  assert.NoError(t, tree.Validate())
+
+  tree = NewTreeWithComparator(ReverseComparator(DefaultComparator))
+  for i:=1; i<=7; i++ { tree.Set(IntKey(i), i) }
+  assert.NoError(t, tree.Validate())
+}
+
+func TestValidateOrder(t *testing.T) {
+  tree := NewTree()
+  tree.root = getTestTreeBalanced(1)
+
+  // 4 -> 2 -> 3 becomes 4 -> 2 -> 9, which is greater than the root
+  tree.root.Left.Right.Key = IntKey(9)
+
+  err := tree.Validate()
+  var validationError *ValidationError
+  assert.True(t, errors.As(err, &validationError))
+  assert.Equal(t, 1, len(validationError.Problems))
+
+  problem := validationError.Problems[0]
+  assert.Equal(t, ProblemOrder, problem.Kind)
+  assert.Equal(t, []Comparable{ IntKey(4), IntKey(2), IntKey(9) }, problem.Path)
+  assert.Equal(t, "order violation at [4 2 9]: key 9 is not less than ancestor key 4", problem.String())
+  assert.Equal(t, "binarytree: tree is invalid: 1 problem(s): order violation at [4 2 9]: key 9 is not less than ancestor key 4", err.Error())
+
+  // Right side
+  tree.root = getTestTreeBalanced(1)
+  tree.root.Right.Left.Key = IntKey(0)
+
+  err = tree.Validate()
+  assert.True(t, errors.As(err, &validationError))
+  assert.Equal(t, 1, len(validationError.Problems))
+  assert.Equal(t, "key 0 is not greater than ancestor key 4", validationError.Problems[0].Message)
+}
+
+func TestValidateDuplicate(t *testing.T) {
+  tree := NewTree()
+  tree.root = getTestTreeBalanced(1)
+  tree.root.Right.Right.Key = IntKey(6)
+
+  err := tree.Validate()
+  var validationError *ValidationError
+  assert.True(t, errors.As(err, &validationError))
+  assert.Equal(t, 1, len(validationError.Problems))
+  assert.Equal(t, ProblemDuplicateKey, validationError.Problems[0].Kind)
+  assert.Equal(t, []Comparable{ IntKey(4), IntKey(6), IntKey(6) }, validationError.Problems[0].Path)
+}
+
+func TestValidateNilKey(t *testing.T) {
+  tree := NewTree()
+  tree.root = getTestTreeBalanced(1)
+  tree.root.Left.Key = nil
+
+  err := tree.Validate()
+  var validationError *ValidationError
+  assert.True(t, errors.As(err, &validationError))
+  assert.Equal(t, 1, len(validationError.Problems))
+  assert.Equal(t, ProblemNilKey, validationError.Problems[0].Kind)
+  assert.Equal(t, []Comparable{ IntKey(4), nil }, validationError.Problems[0].Path)
+
+  // Children of a nil key are still checked against the bounds above it
+  tree.root.Left.Right.Key = IntKey(5)
+  err = tree.Validate()
+  assert.True(t, errors.As(err, &validationError))
+  assert.Equal(t, 2, len(validationError.Problems))
+  assert.Equal(t, ProblemOrder, validationError.Problems[1].Kind)
+  assert.Equal(t, []Comparable{ IntKey(4), nil, IntKey(5) }, validationError.Problems[1].Path)
+}
+
+func TestValidateCycle(t *testing.T) {
+  tree := NewTree()
+  tree.root = getTestTreeBalanced(1)
+  tree.root.Left.Left.Left = tree.root
+
+  err := tree.Validate()
+  var validationError *ValidationError
+  assert.True(t, errors.As(err, &validationError))
+  assert.Equal(t, 1, len(validationError.Problems))
+  assert.Equal(t, ProblemCycle, validationError.Problems[0].Kind)
+  assert.Equal(t, []Comparable{ IntKey(4), IntKey(2), IntKey(1), IntKey(4) }, validationError.Problems[0].Path)
+
+  // Shared subtree
+  tree.root = getTestTreeBalanced(1)
+  tree.root.Right.Left.Left = tree.root.Right.Right
+
+  err = tree.Validate()
+  assert.True(t, errors.As(err, &validationError))
+  kinds := []ProblemKind{}
+  for _, problem := range validationError.Problems { kinds = append(kinds, problem.Kind) }
+  assert.Contains(t, kinds, ProblemCycle)
+}
+
+func TestValidateKeyType(t *testing.T) {
+  tree := NewTree()
+  tree.root = getTestTreeBalanced(1)
+  tree.root.Right.Right.Key = StringKey("6")
+
+  err := tree.Validate()
+  var validationError *ValidationError
+  assert.True(t, errors.As(err, &validationError))
+  problem := validationError.Problems[0]
+  assert.Equal(t, ProblemKeyType, problem.Kind)
+  assert.Equal(t, []Comparable{ IntKey(4), IntKey(6), StringKey("6") }, problem.Path)
+  assert.Equal(t, "key 6 is a binarytree.StringKey, the tree holds binarytree.IntKey keys", problem.Message)
+}
+
+func TestProblemKindString(t *testing.T) {
+  assert.Equal(t, "order violation", ProblemOrder.String())
+  assert.Equal(t, "cycle", ProblemCycle.String())
+  assert.Equal(t, "nil key", ProblemNilKey.String())
+  assert.Equal(t, "duplicate key", ProblemDuplicateKey.String())
+  assert.Equal(t, "stale hash", ProblemHash.String())
+  assert.Equal(t, "mixed key types", ProblemKeyType.String())
+  assert.Equal(t, "ProblemKind(99)", ProblemKind(99).String())
+}
@@ -0,0 +1,147 @@
+package binarytree
+
+import(
+  "sync"
+  "sync/atomic"
+)
+
+// EventOp is the kind of mutation described by an Event
+type EventOp int
+
+const (
+  // A key was added, or its value replaced, by Set.
+  EventSet EventOp = iota
+  // A key was removed by Clear.
+  EventClear
+)
+
+// Return the name of the operation
+func (me EventOp) String() string {
+  switch me {
+    case EventSet: return "set"
+    case EventClear: return "clear"
+  }
+  return "unknown"
+}
+
+// Event describes a single mutation of a Tree. OldValue is nil if the key was not present,
+// and NewValue is nil for EventClear.
+type Event struct {
+  Op EventOp
+  Key Comparable
+  OldValue interface{}
+  NewValue interface{}
+}
+
+// OverflowPolicy decides what a Subscription does with an event when its buffer is full
+type OverflowPolicy int
+
+const (
+  // Block the mutating call until the subscriber receives the event or closes the subscription.
+  OverflowBlock OverflowPolicy = iota
+  // Discard the event and count it in Subscription.Dropped.
+  OverflowDrop
+)
+
+// WatchOptions controls how a Subscription delivers events. The zero value delivers events
+// over an unbuffered channel, blocking the mutating call until each is received.
+type WatchOptions struct {
+  // The capacity of the events channel.
+  Buffer int
+  // What to do when the events channel is full.
+  Policy OverflowPolicy
+  // If set, call Callback synchronously from the mutating call instead of using a channel.
+  Callback func(event Event)
+}
+
+// Subscription delivers the events for mutations within a range of keys, as returned by Tree.Watch
+type Subscription struct {
+  from Comparable
+  to Comparable
+  events chan Event
+  callback func(event Event)
+  policy OverflowPolicy
+  done chan struct{}
+  once sync.Once
+  mutex sync.Mutex
+  closed atomic.Bool
+  dropped atomic.Uint64
+}
+
+// Subscribe to mutations of keys in the range from, to inclusive. Either bound may be nil for an
+// open range. opts may be nil. Events are delivered in the order the mutations are made.
+//
+// With nil or zero WatchOptions, events are sent on an unbuffered channel and each mutating call
+// blocks until its event is received, so the goroutine reading Events must not itself mutate the
+// tree. Set a Buffer with OverflowDrop, or a Callback, to never block.
+func (me *Tree) Watch(from Comparable, to Comparable, opts *WatchOptions) *Subscription {
+  if opts == nil { opts = &WatchOptions{} }
+  sub := &Subscription{ from: from, to: to, callback: opts.Callback, policy: opts.Policy, done: make(chan struct{}) }
+  if sub.callback == nil { sub.events = make(chan Event, opts.Buffer) }
+  me.watchers = append(me.watchers, sub)
+  return sub
+}
+
+// Return the channel events are delivered on, or nil if the subscription uses a callback.
+// The channel is closed when the subscription is closed.
+func (me *Subscription) Events() <-chan Event {
+  return me.events
+}
+
+// Return the number of events discarded under the OverflowDrop policy.
+func (me *Subscription) Dropped() uint64 {
+  return me.dropped.Load()
+}
+
+// Unsubscribe. No events are sent on the channel after Close returns, and any mutating call blocked
+// delivering to this subscription is released. The callback is not called for mutations made after
+// Close returns, but a call already under way in another goroutine may still be running or about to
+// start. Close may be called more than once, including from the callback.
+func (me *Subscription) Close() {
+  me.once.Do(func() {
+    me.closed.Store(true)
+    close(me.done)
+    me.mutex.Lock()
+    if me.events != nil { close(me.events) }
+    me.mutex.Unlock()
+  })
+}
+
+func (me *Subscription) deliver(event Event) {
+  if me.callback != nil {
+    if !me.closed.Load() { me.callback(event) }
+    return
+  }
+  me.mutex.Lock()
+  defer me.mutex.Unlock()
+  if me.closed.Load() { return }
+  if me.policy == OverflowDrop {
+    select {
+      case me.events <- event:
+      default: me.dropped.Add(1)
+    }
+    return
+  }
+  select {
+    case me.events <- event:
+    case <-me.done:
+  }
+}
+
+// Deliver an event to every subscription watching its key, dropping closed subscriptions.
+func (me *Tree) emit(event Event) {
+  if len(me.watchers) == 0 { return }
+  cmp := me.Comparator()
+  watchers := me.watchers[:0]
+  for _, sub := range me.watchers {
+    if sub.closed.Load() { continue }
+    watchers = append(watchers, sub)
+  }
+  for i:=len(watchers); i<len(me.watchers); i++ { me.watchers[i] = nil }
+  me.watchers = watchers
+  for _, sub := range append([]*Subscription{}, watchers...) {
+    if sub.from != nil && cmp(event.Key, sub.from) < 0 { continue }
+    if sub.to != nil && cmp(event.Key, sub.to) > 0 { continue }
+    sub.deliver(event)
+  }
+}
@@ -0,0 +1,140 @@
+package binarytree
+
+import (
+  "testing"
+  "time"
+  "github.com/stretchr/testify/assert"
+)
+
+func TestWatchChannel(t *testing.T) {
+  tree := NewTree()
+  sub := tree.Watch(IntKey(2), IntKey(5), &WatchOptions{ Buffer: 10 })
+
+  tree.Set(IntKey(1), "one")
+  tree.Set(IntKey(3), "three")
+  tree.Set(IntKey(3), "THREE")
+  tree.Set(IntKey(6), "six")
+  tree.Clear(IntKey(3))
+  tree.Clear(IntKey(4))
+  tree.Clear(IntKey(1))
+
+  assert.Equal(t, Event{ Op: EventSet, Key: IntKey(3), NewValue: "three" }, <-sub.Events())
+  assert.Equal(t, Event{ Op: EventSet, Key: IntKey(3), OldValue: "three", NewValue: "THREE" }, <-sub.Events())
+  assert.Equal(t, Event{ Op: EventClear, Key: IntKey(3), OldValue: "THREE" }, <-sub.Events())
+  assert.Equal(t, 0, len(sub.Events()))
+
+  sub.Close()
+  _, open := <-sub.Events()
+  assert.False(t, open)
+
+  // Closed subscriptions are dropped on the next mutation
+  tree.Set(IntKey(3), "three")
+  assert.Equal(t, 0, len(tree.watchers))
+  sub.Close()
+}
+
+func TestWatchOpenRange(t *testing.T) {
+  tree := NewTree()
+  all := tree.Watch(nil, nil, &WatchOptions{ Buffer: 10 })
+  below := tree.Watch(nil, IntKey(2), &WatchOptions{ Buffer: 10 })
+  above := tree.Watch(IntKey(2), nil, &WatchOptions{ Buffer: 10 })
+
+  tree.Set(IntKey(1), 1)
+  tree.Set(IntKey(2), 2)
+  tree.Set(IntKey(3), 3)
+
+  assert.Equal(t, 3, len(all.Events()))
+  assert.Equal(t, 2, len(below.Events()))
+  assert.Equal(t, 2, len(above.Events()))
+}
+
+func TestWatchCallback(t *testing.T) {
+  tree := NewTree()
+  events := []Event{}
+  sub := tree.Watch(nil, nil, &WatchOptions{ Callback: func(event Event) { events = append(events, event) } })
+
+  assert.Nil(t, sub.Events())
+
+  tree.Set(StringKey("a"), 1)
+  tree.DeletePrefix(StringKey("a"))
+
+  assert.Equal(t, []Event{
+    { Op: EventSet, Key: StringKey("a"), NewValue: 1 },
+    { Op: EventClear, Key: StringKey("a"), OldValue: 1 },
+  }, events)
+
+  sub.Close()
+  tree.Set(StringKey("b"), 2)
+  assert.Equal(t, 2, len(events))
+}
+
+func TestWatchCallbackClose(t *testing.T) {
+  tree := NewTree()
+  count := 0
+  var sub *Subscription
+  sub = tree.Watch(nil, nil, &WatchOptions{ Callback: func(event Event) {
+    count++
+    sub.Close()
+  } })
+
+  tree.Set(IntKey(1), 1)
+  tree.Set(IntKey(2), 2)
+
+  assert.Equal(t, 1, count)
+}
+
+func TestWatchDrop(t *testing.T) {
+  tree := NewTree()
+  sub := tree.Watch(nil, nil, &WatchOptions{ Buffer: 2, Policy: OverflowDrop })
+
+  for i:=0; i<5; i++ { tree.Set(IntKey(i), i) }
+
+  assert.Equal(t, uint64(3), sub.Dropped())
+  assert.Equal(t, IntKey(0), (<-sub.Events()).Key)
+  assert.Equal(t, IntKey(1), (<-sub.Events()).Key)
+}
+
+func TestWatchBlock(t *testing.T) {
+  tree := NewTree()
+  sub := tree.Watch(nil, nil, nil)
+
+  received := make(chan Event, 2)
+  go func() {
+    for event := range sub.Events() { received <- event }
+  }()
+
+  tree.Set(IntKey(1), 1)
+  tree.Set(IntKey(2), 2)
+
+  assert.Equal(t, IntKey(1), (<-received).Key)
+  assert.Equal(t, IntKey(2), (<-received).Key)
+  sub.Close()
+}
+
+func TestWatchCloseReleasesBlockedMutation(t *testing.T) {
+  tree := NewTree()
+  sub := tree.Watch(nil, nil, nil)
+
+  done := make(chan bool)
+  go func() {
+    tree.Set(IntKey(1), 1)
+    done <- true
+  }()
+
+  select {
+    case <-done:
+      assert.Fail(t, "Set should block until the event is received")
+    case <-time.After(20 * time.Millisecond):
+  }
+
+  sub.Close()
+  <-done
+  found, _ := tree.Get(IntKey(1))
+  assert.True(t, found)
+}
+
+func TestEventOpString(t *testing.T) {
+  assert.Equal(t, "set", EventSet.String())
+  assert.Equal(t, "clear", EventClear.String())
+  assert.Equal(t, "unknown", EventOp(9).String())
+}